@@ -13,6 +13,12 @@ type VaultSecretSpec struct {
 	// ignored. If the operator is configured using the token auth method this
 	// property has no effect.
 	VaultRole string `json:"vaultRole,omitempty"`
+	// ServiceAccountName is the name of the service account in the namespace
+	// of the secret, which is used to authenticate against Vault with the
+	// vaultRole, when the operator is configured to request service account
+	// tokens via the TokenRequest API (VAULT_KUBERNETES_TOKEN_REQUEST). If it
+	// is not set the "default" service account is used.
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
 	// VaultNamespace can be used to specify the Vault namespace for a secret.
 	// When this value is set, the X-Vault-Namespace header will be set for the
 	// request. More information regarding namespaces can be found in the Vault
@@ -47,15 +53,26 @@ type VaultSecretSpec struct {
 	// for the 'kv' secret engine.
 	Paths []string `json:"paths,omitempty"`
 	// SecretEngine specifies the type of the Vault secret engine in which the
-	// secret is stored. Currently the 'KV Secrets Engine - Version 1' and
-	// 'KV Secrets Engine - Version 2' are supported. The value must be 'kv'. If
-	// the value is omitted or an other values is used the Vault Secrets
-	// Operator will try to use the KV secret engine.
+	// secret is stored. Currently the 'KV Secrets Engine - Version 1', the
+	// 'KV Secrets Engine - Version 2', the 'PKI Secrets Engine', the
+	// 'Database Secrets Engine', the 'AWS Secrets Engine', the 'Transit
+	// Secrets Engine' and the 'SSH Secrets Engine' are supported. The value
+	// must be 'kv', 'pki', 'database', 'aws', 'transit' or 'ssh'. If the
+	// value is omitted or an other values is used the Vault Secrets Operator
+	// will try to use the KV secret engine.
 	SecretEngine string `json:"secretEngine,omitempty"`
 	// EngineOptions specifies options for the engine.
 	EngineOptions map[string]string `json:"engineOptions,omitempty"`
-	// Role specifies the role to use with PKI engine
+	// Role specifies the role to use with the PKI, database, AWS and SSH
+	// engine.
 	Role string `json:"role,omitempty"`
+	// TransitKey is the name of the encryption key, which is used to decrypt
+	// the ciphertexts with the transit engine.
+	TransitKey string `json:"transitKey,omitempty"`
+	// Ciphertexts is a map of keys and ciphertexts (e.g. "vault:v1:..."),
+	// which are decrypted with the transit engine. The decrypted plaintexts
+	// are added to the Kubernetes secret under the corresponding keys.
+	Ciphertexts map[string]string `json:"ciphertexts,omitempty"`
 	// Type is the type of the Kubernetes secret, which will be created by the
 	// Vault Secrets Operator.
 	Type corev1.SecretType `json:"type"`
@@ -72,11 +89,86 @@ type VaultSecretSpec struct {
 	// data to get double encoded. This flag will skip the base64 encode which
 	// is needed for string data to avoid the double encode problem.
 	IsBinary bool `json:"isBinary,omitempty"`
+	// SkipRevoke can be set to true to not revoke the lease or the certificate
+	// which was issued by Vault, when the VaultSecret is deleted. This should
+	// be used when the credentials are shared with other workloads, which are
+	// not managed by the VaultSecret.
+	SkipRevoke bool `json:"skipRevoke,omitempty"`
+	// RevokePreviousCertificate can be set to true to revoke the previous
+	// certificate, when a new certificate was issued by the PKI secrets
+	// engine. The previous certificate is revoked after the
+	// RevokeGracePeriod, so that workloads have enough time to switch to the
+	// new certificate.
+	RevokePreviousCertificate bool `json:"revokePreviousCertificate,omitempty"`
+	// RevokeGracePeriod is the duration after which the previous certificate
+	// is revoked (e.g. "10m"). If omitted the previous certificate is revoked
+	// directly after the Kubernetes secret was updated.
+	RevokeGracePeriod *metav1.Duration `json:"revokeGracePeriod,omitempty"`
+	// Keystores can be used to add a PKCS#12 keystore ("keystore.p12") and
+	// Java keystores ("keystore.jks" and "truststore.jks") to the Kubernetes
+	// secret. The keystores are created from the certificate, private key and
+	// CA chain issued by the PKI secrets engine. Keystores are only supported
+	// for the 'pki' secret engine.
+	Keystores *VaultSecretKeystores `json:"keystores,omitempty"`
+	// SkipTLSKeyMapping can be set to true to not add the "tls.crt",
+	// "tls.key" and "ca.crt" keys to a Kubernetes secret of the type
+	// "kubernetes.io/tls", which is created by the 'pki' secret engine.
+	SkipTLSKeyMapping bool `json:"skipTLSKeyMapping,omitempty"`
+}
+
+// VaultSecretKeystores configures the keystores, which are created for a
+// certificate issued by the PKI secrets engine.
+type VaultSecretKeystores struct {
+	// Password is the password, which is used to protect the keystores.
+	Password string `json:"password,omitempty"`
+	// PasswordSecretRef references a key of a Kubernetes secret in the
+	// namespace of the VaultSecret, which contains the password for the
+	// keystores. It can be used instead of the Password field.
+	PasswordSecretRef *corev1.SecretKeySelector `json:"passwordSecretRef,omitempty"`
 }
 
 // VaultSecretStatus defines the observed state of VaultSecret
 type VaultSecretStatus struct {
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// ObservedGeneration is the generation of the VaultSecret, which was used
+	// for the last successful reconciliation. It is used to detect changes of
+	// the spec, which require new credentials for a lease which is still
+	// valid.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Lease is the lease of the dynamic secret, which is stored in the
+	// Kubernetes secret. It is only set for secret engines which return a
+	// lease, e.g. the 'database' and 'aws' secret engines.
+	Lease *VaultSecretLease `json:"lease,omitempty"`
+	// SerialNumber is the serial number of the certificate, which is stored in
+	// the Kubernetes secret. It is only set for the 'pki' secret engine.
+	SerialNumber string `json:"serialNumber,omitempty"`
+	// PendingRevocations contains the previous certificates, which were
+	// replaced by a new certificate and which will be revoked after the grace
+	// period. It is only set for the 'pki' secret engine, when the
+	// revokePreviousCertificate property is set.
+	PendingRevocations []VaultSecretRevocation `json:"pendingRevocations,omitempty"`
+}
+
+// VaultSecretLease is the lease of a dynamic secret, which was returned by
+// Vault.
+type VaultSecretLease struct {
+	// ID is the lease ID returned by Vault.
+	ID string `json:"id,omitempty"`
+	// Duration is the lease duration in seconds, when the lease was issued. It
+	// is used as increment when the lease is renewed.
+	Duration int `json:"duration,omitempty"`
+	// Renewable indicates if the lease can be renewed.
+	Renewable bool `json:"renewable,omitempty"`
+	// ExpireTime is the time when the lease expires.
+	ExpireTime metav1.Time `json:"expireTime,omitempty"`
+}
+
+// VaultSecretRevocation is a certificate, which should be revoked.
+type VaultSecretRevocation struct {
+	// SerialNumber is the serial number of the certificate.
+	SerialNumber string `json:"serialNumber"`
+	// RevokeTime is the time after which the certificate is revoked.
+	RevokeTime metav1.Time `json:"revokeTime"`
 }
 
 // +kubebuilder:object:root=true
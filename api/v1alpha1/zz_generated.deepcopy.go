@@ -5,6 +5,7 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
@@ -36,6 +37,42 @@ func (in *VaultSecret) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultSecretKeystores) DeepCopyInto(out *VaultSecretKeystores) {
+	*out = *in
+	if in.PasswordSecretRef != nil {
+		in, out := &in.PasswordSecretRef, &out.PasswordSecretRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultSecretKeystores.
+func (in *VaultSecretKeystores) DeepCopy() *VaultSecretKeystores {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultSecretKeystores)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultSecretLease) DeepCopyInto(out *VaultSecretLease) {
+	*out = *in
+	in.ExpireTime.DeepCopyInto(&out.ExpireTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultSecretLease.
+func (in *VaultSecretLease) DeepCopy() *VaultSecretLease {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultSecretLease)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VaultSecretList) DeepCopyInto(out *VaultSecretList) {
 	*out = *in
@@ -68,6 +105,22 @@ func (in *VaultSecretList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultSecretRevocation) DeepCopyInto(out *VaultSecretRevocation) {
+	*out = *in
+	in.RevokeTime.DeepCopyInto(&out.RevokeTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultSecretRevocation.
+func (in *VaultSecretRevocation) DeepCopy() *VaultSecretRevocation {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultSecretRevocation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VaultSecretSpec) DeepCopyInto(out *VaultSecretSpec) {
 	*out = *in
@@ -95,6 +148,23 @@ func (in *VaultSecretSpec) DeepCopyInto(out *VaultSecretSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.Ciphertexts != nil {
+		in, out := &in.Ciphertexts, &out.Ciphertexts
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.RevokeGracePeriod != nil {
+		in, out := &in.RevokeGracePeriod, &out.RevokeGracePeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Keystores != nil {
+		in, out := &in.Keystores, &out.Keystores
+		*out = new(VaultSecretKeystores)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultSecretSpec.
@@ -117,6 +187,18 @@ func (in *VaultSecretStatus) DeepCopyInto(out *VaultSecretStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Lease != nil {
+		in, out := &in.Lease, &out.Lease
+		*out = new(VaultSecretLease)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PendingRevocations != nil {
+		in, out := &in.PendingRevocations, &out.PendingRevocations
+		*out = make([]VaultSecretRevocation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultSecretStatus.
@@ -1,15 +1,18 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
 	"regexp"
 	"strings"
+	"time"
 
 	ricobergerdev1alpha1 "github.com/ricoberger/vault-secrets-operator/api/v1alpha1"
 	"github.com/ricoberger/vault-secrets-operator/internal/controller"
+	"github.com/ricoberger/vault-secrets-operator/internal/tracing"
 	"github.com/ricoberger/vault-secrets-operator/internal/vault"
 
 	// +kubebuilder:scaffold:imports
@@ -42,9 +45,19 @@ func main() {
 	var metricsAddr string
 	var probeAddr string
 	var enableLeaderElection bool
+	var maxConcurrentReconciles int
+	var clientPoolIdleTimeout time.Duration
+	var metricsObjectLabels bool
+	var tracingEndpoint string
+	var tracingInsecure bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false, "Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1, "The maximum number of VaultSecrets which can be reconciled in parallel.")
+	flag.DurationVar(&clientPoolIdleTimeout, "client-pool-idle-timeout", 15*time.Minute, "The time after which an unused Vault client for a vaultRole without valid leases is evicted and its token is revoked. A value of 0 disables the client pool.")
+	flag.BoolVar(&metricsObjectLabels, "metrics-object-labels", true, "Add the namespace and name of the VaultSecret as labels to the metrics. Disable it to limit the number of series in clusters with many VaultSecrets.")
+	flag.StringVar(&tracingEndpoint, "tracing-endpoint", "", "The OTLP gRPC endpoint (e.g. otel-collector:4317) to which the traces are exported. If it is empty, tracing is disabled.")
+	flag.BoolVar(&tracingInsecure, "tracing-insecure", false, "Disable TLS for the connection to the OTLP endpoint.")
 	opts := zap.Options{
 		Development: false,
 	}
@@ -53,19 +66,21 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
-	// Create the API client for Vault and start the renew process for the
-	// token in a new goroutine.
-	err := vault.InitSharedClient()
+	shutdownTracing, err := tracing.Setup(context.Background(), tracingEndpoint, tracingInsecure)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+
+	// Create the API client for Vault. The renew process for the token is
+	// added to the manager below.
+	err = vault.InitSharedClient()
 	if err != nil {
 		ctrl.Log.Error(err, "Could not create API client for Vault")
 		os.Exit(1)
 	}
 
-	if vault.SharedClient != nil {
-		if vault.SharedClient.PerformRenewToken() {
-			go vault.SharedClient.RenewToken()
-		}
-	} else {
+	if vault.SharedClient == nil {
 		ctrl.Log.Info("Shared client wasn't initialized, each secret must be use the vaultRole property")
 	}
 
@@ -136,10 +151,34 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Renew the token of the shared client until the manager is stopped.
+	if vault.SharedClient != nil && vault.SharedClient.PerformRenewToken() {
+		if err := mgr.Add(vault.NewTokenRenewer(vault.SharedClient)); err != nil {
+			setupLog.Error(err, "unable to add Vault token renewer")
+			os.Exit(1)
+		}
+	}
+
+	// Create the pool for the Vault clients of secrets with the vaultRole
+	// property. The pool is added to the manager, so that the tokens are
+	// renewed in the background and revoked on shutdown.
+	var clientPool *vault.ClientPool
+	if clientPoolIdleTimeout > 0 {
+		clientPool = vault.NewClientPool(clientPoolIdleTimeout)
+		if err := mgr.Add(clientPool); err != nil {
+			setupLog.Error(err, "unable to add Vault client pool")
+			os.Exit(1)
+		}
+	}
+
 	if err = (&controller.VaultSecretReconciler{
-		Client:          mgr.GetClient(),
-		Scheme:          mgr.GetScheme(),
-		NamespaceFilter: reconcilerFilter,
+		Client:                  mgr.GetClient(),
+		Scheme:                  mgr.GetScheme(),
+		NamespaceFilter:         reconcilerFilter,
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+		ClientPool:              clientPool,
+		OmitMetricsObjectLabels: !metricsObjectLabels,
+		Recorder:                mgr.GetEventRecorder("vault-secrets-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "VaultSecret")
 		os.Exit(1)
@@ -151,7 +190,7 @@ func main() {
 			return nil
 		}
 
-		return vault.SharedClient.GetHealth(10)
+		return vault.SharedClient.GetHealth(5 * time.Minute)
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to set up health check")
@@ -163,7 +202,7 @@ func main() {
 			return nil
 		}
 
-		return vault.SharedClient.GetHealth(5)
+		return vault.SharedClient.GetHealth(2 * time.Minute)
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to set up ready check")
@@ -175,6 +214,13 @@ func main() {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
+
+	// Export the remaining spans before the operator exits.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		setupLog.Error(err, "problem shutting down tracing")
+	}
 }
 
 // getWatchNamespace returns the Namespace the operator should be watching for
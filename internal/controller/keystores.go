@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/vault-secrets-operator/api/v1alpha1"
+
+	"github.com/pavlo-v-chernykh/keystore-go/v4"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+const (
+	keystoreP12Key   = "keystore.p12"
+	keystoreJKSKey   = "keystore.jks"
+	truststoreJKSKey = "truststore.jks"
+
+	// keystoreAlias is the alias of the private key entry in the Java
+	// keystore.
+	keystoreAlias = "certificate"
+)
+
+// keystorePassword returns the password for the keystores of the VaultSecret.
+// The password is either set in the spec of the VaultSecret or read from the
+// referenced Kubernetes secret in the namespace of the VaultSecret.
+func (r *VaultSecretReconciler) keystorePassword(ctx context.Context, instance *ricobergerdev1alpha1.VaultSecret) (string, error) {
+	ref := instance.Spec.Keystores.PasswordSecretRef
+	if ref == nil {
+		return instance.Spec.Keystores.Password, nil
+	}
+
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: instance.Namespace}, secret)
+	if err != nil {
+		return "", fmt.Errorf("could not get keystore password secret: %w", err)
+	}
+
+	password, ok := secret.Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("key %s not found in keystore password secret %s", ref.Key, ref.Name)
+	}
+
+	return string(password), nil
+}
+
+// keystores creates a PKCS#12 keystore and a Java keystore containing the
+// private key and the certificate chain and a Java truststore containing the
+// CA certificates from the given secret data of the PKI secrets engine. The CA
+// certificates are taken from the "ca_chain" field and if it is empty from the
+// "issuing_ca" field. All keystores are protected with the given password.
+func keystores(data map[string][]byte, password string) (map[string][]byte, error) {
+	certificates, err := parseCertificates(data["certificate"])
+	if err != nil {
+		return nil, fmt.Errorf("could not parse certificate: %w", err)
+	}
+	if len(certificates) == 0 {
+		return nil, fmt.Errorf("certificate not found")
+	}
+	certificate := certificates[0]
+
+	caCertificates, err := parseCertificates(data["ca_chain"])
+	if err != nil {
+		return nil, fmt.Errorf("could not parse ca_chain: %w", err)
+	}
+	if len(caCertificates) == 0 {
+		caCertificates, err = parseCertificates(data["issuing_ca"])
+		if err != nil {
+			return nil, fmt.Errorf("could not parse issuing_ca: %w", err)
+		}
+	}
+
+	privateKey, err := parsePrivateKey(data["private_key"])
+	if err != nil {
+		return nil, fmt.Errorf("could not parse private key: %w", err)
+	}
+
+	p12, err := pkcs12.Modern.Encode(privateKey, certificate, caCertificates, password)
+	if err != nil {
+		return nil, fmt.Errorf("could not create PKCS#12 keystore: %w", err)
+	}
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal private key: %w", err)
+	}
+
+	chain := make([]keystore.Certificate, 0, len(caCertificates)+1)
+	chain = append(chain, keystore.Certificate{Type: "X509", Content: certificate.Raw})
+	for _, caCertificate := range caCertificates {
+		chain = append(chain, keystore.Certificate{Type: "X509", Content: caCertificate.Raw})
+	}
+
+	ks := keystore.New(keystore.WithCustomRandomNumberGenerator(rand.Reader))
+	err = ks.SetPrivateKeyEntry(keystoreAlias, keystore.PrivateKeyEntry{
+		CreationTime:     certificate.NotBefore,
+		PrivateKey:       pkcs8,
+		CertificateChain: chain,
+	}, []byte(password))
+	if err != nil {
+		return nil, fmt.Errorf("could not create Java keystore: %w", err)
+	}
+
+	ts := keystore.New(keystore.WithCustomRandomNumberGenerator(rand.Reader))
+	for i, caCertificate := range caCertificates {
+		err = ts.SetTrustedCertificateEntry(fmt.Sprintf("ca-%d", i), keystore.TrustedCertificateEntry{
+			CreationTime: caCertificate.NotBefore,
+			Certificate:  keystore.Certificate{Type: "X509", Content: caCertificate.Raw},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not create Java truststore: %w", err)
+		}
+	}
+
+	var jks, truststore bytes.Buffer
+	if err := ks.Store(&jks, []byte(password)); err != nil {
+		return nil, fmt.Errorf("could not store Java keystore: %w", err)
+	}
+	if err := ts.Store(&truststore, []byte(password)); err != nil {
+		return nil, fmt.Errorf("could not store Java truststore: %w", err)
+	}
+
+	return map[string][]byte{
+		keystoreP12Key:   p12,
+		keystoreJKSKey:   jks.Bytes(),
+		truststoreJKSKey: truststore.Bytes(),
+	}, nil
+}
+
+// parseCertificates parses all PEM encoded certificates from the given data.
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certificates []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certificates, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certificates = append(certificates, certificate)
+	}
+}
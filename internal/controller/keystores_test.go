@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/pavlo-v-chernykh/keystore-go/v4"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// testPKIData returns the secret data of the PKI secrets engine for a leaf
+// certificate, which is signed by a new CA, and a private key of the given
+// type.
+func testPKIData(t *testing.T, privateKeyType string) map[string][]byte {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ca key: %v", err)
+	}
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "example-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("failed to create ca certificate: %v", err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatalf("failed to parse ca certificate: %v", err)
+	}
+
+	key, keyPEM, _, err := pkiPrivateKey(nil, map[string]string{"private_key_type": privateKeyType})
+	if err != nil {
+		t.Fatalf("failed to generate private key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "www.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, key.Public(), caKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})
+
+	return map[string][]byte{
+		"certificate": pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		"issuing_ca":  caPEM,
+		"ca_chain":    caPEM,
+		"private_key": keyPEM,
+	}
+}
+
+// TestKeystores verifies that the PKCS#12 keystore and the Java key- and
+// truststore can be loaded with the configured password and contain the
+// certificate chain.
+func TestKeystores(t *testing.T) {
+	for _, privateKeyType := range []string{"rsa", "ec", "ed25519"} {
+		t.Run(privateKeyType, func(t *testing.T) {
+			data := testPKIData(t, privateKeyType)
+
+			got, err := keystores(data, "changeit")
+			if err != nil {
+				t.Fatalf("keystores returned an error: %v", err)
+			}
+
+			_, certificate, caCertificates, err := pkcs12.DecodeChain(got[keystoreP12Key], "changeit")
+			if err != nil {
+				t.Fatalf("failed to decode PKCS#12 keystore: %v", err)
+			}
+			if certificate.Subject.CommonName != "www.example.com" {
+				t.Errorf("certificate common name = %q, want www.example.com", certificate.Subject.CommonName)
+			}
+			if len(caCertificates) != 1 || caCertificates[0].Subject.CommonName != "example-ca" {
+				t.Errorf("unexpected ca certificates in PKCS#12 keystore: %v", caCertificates)
+			}
+
+			ks := keystore.New()
+			if err := ks.Load(bytes.NewReader(got[keystoreJKSKey]), []byte("changeit")); err != nil {
+				t.Fatalf("failed to load Java keystore: %v", err)
+			}
+			entry, err := ks.GetPrivateKeyEntry(keystoreAlias, []byte("changeit"))
+			if err != nil {
+				t.Fatalf("failed to get private key entry: %v", err)
+			}
+			if len(entry.CertificateChain) != 2 {
+				t.Errorf("certificate chain length = %d, want 2", len(entry.CertificateChain))
+			}
+
+			ts := keystore.New()
+			if err := ts.Load(bytes.NewReader(got[truststoreJKSKey]), []byte("changeit")); err != nil {
+				t.Fatalf("failed to load Java truststore: %v", err)
+			}
+			if !ts.IsTrustedCertificateEntry("ca-0") {
+				t.Error("expected the ca certificate in the Java truststore")
+			}
+		})
+	}
+}
+
+// TestKeystoresIssuingCA verifies that the issuing CA is used for the
+// truststore, when the secret data does not contain a CA chain.
+func TestKeystoresIssuingCA(t *testing.T) {
+	data := testPKIData(t, "ec")
+	delete(data, "ca_chain")
+
+	got, err := keystores(data, "changeit")
+	if err != nil {
+		t.Fatalf("keystores returned an error: %v", err)
+	}
+
+	ts := keystore.New()
+	if err := ts.Load(bytes.NewReader(got[truststoreJKSKey]), []byte("changeit")); err != nil {
+		t.Fatalf("failed to load Java truststore: %v", err)
+	}
+	if aliases := ts.Aliases(); len(aliases) != 1 {
+		t.Errorf("unexpected truststore aliases: %v", aliases)
+	}
+}
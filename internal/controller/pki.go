@@ -0,0 +1,368 @@
+package controller
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/vault-secrets-operator/api/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	pkiModeIssue = "issue"
+	pkiModeSign  = "sign"
+
+	// pkiRevokeRetryInterval is the time after which the revocation of a
+	// previous certificate is retried, when it failed.
+	pkiRevokeRetryInterval = time.Minute
+)
+
+// pkiOperatorOptions are the engine options of the PKI engine, which are only
+// used by the operator and must not be sent to Vault.
+var pkiOperatorOptions = []string{"mode", "private_key_type", "private_key_bits", "reuse_private_key"}
+
+// pkiMode returns the configured mode for the PKI engine. By default
+// certificates are issued by Vault ("issue"). In the "sign" mode the operator
+// generates the private key and only sends a CSR to Vault.
+func pkiMode(options map[string]string) string {
+	if mode, ok := options["mode"]; ok && mode != "" {
+		return mode
+	}
+	return pkiModeIssue
+}
+
+// pkiVaultOptions returns a copy of the engine options without the options
+// which are only used by the operator.
+func pkiVaultOptions(options map[string]string) map[string]string {
+	vaultOptions := make(map[string]string, len(options))
+	for k, v := range options {
+		vaultOptions[k] = v
+	}
+	for _, k := range pkiOperatorOptions {
+		delete(vaultOptions, k)
+	}
+	return vaultOptions
+}
+
+// pkiPrivateKey returns the private key and its PEM encoding, which is used to
+// create the CSR for the sign mode of the PKI engine. The key type and size are
+// configured via the "private_key_type" (rsa, ec or ed25519) and
+// "private_key_bits" engine options. When the "reuse_private_key" option is set
+// and the existing Secret data contains a private key with the configured type
+// and size, this key is reused, so that the key does not change when the
+// certificate is renewed. The returned key type uses the same values as the
+// "private_key_type" field returned by Vault.
+func pkiPrivateKey(data map[string][]byte, options map[string]string) (crypto.Signer, []byte, string, error) {
+	keyType := options["private_key_type"]
+	if keyType == "" {
+		keyType = "rsa"
+	}
+
+	keyBits := 0
+	if bits, ok := options["private_key_bits"]; ok && bits != "" {
+		var err error
+		keyBits, err = strconv.Atoi(bits)
+		if err != nil {
+			return nil, nil, "", fmt.Errorf("invalid private_key_bits: %w", err)
+		}
+	}
+
+	if reuse, _ := strconv.ParseBool(options["reuse_private_key"]); reuse {
+		if privateKeyPEM, ok := data["private_key"]; ok {
+			if key, err := parsePrivateKey(privateKeyPEM); err == nil && privateKeyMatches(key, keyType, keyBits) {
+				return key, privateKeyPEM, keyType, nil
+			}
+		}
+	}
+
+	var (
+		key   crypto.Signer
+		block *pem.Block
+	)
+
+	switch keyType {
+	case "rsa":
+		if keyBits == 0 {
+			keyBits = 2048
+		}
+		rsaKey, err := rsa.GenerateKey(rand.Reader, keyBits)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		key = rsaKey
+		block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}
+	case "ec":
+		curve, err := ellipticCurve(keyBits)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		ecKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		der, err := x509.MarshalECPrivateKey(ecKey)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		key = ecKey
+		block = &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}
+	case "ed25519":
+		_, edKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(edKey)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		key = edKey
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	default:
+		return nil, nil, "", fmt.Errorf("unsupported private_key_type %q", keyType)
+	}
+
+	return key, pem.EncodeToMemory(block), keyType, nil
+}
+
+// pkiCertificateRequest creates a PEM encoded CSR for the given private key.
+// The subject and the SANs are taken from the "common_name", "alt_names",
+// "ip_sans" and "uri_sans" engine options, which have the same format as for
+// the issue endpoint of the PKI secrets engine.
+func pkiCertificateRequest(key crypto.Signer, options map[string]string) ([]byte, error) {
+	template := &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: options["common_name"]},
+	}
+
+	for _, name := range splitOption(options["alt_names"]) {
+		if strings.Contains(name, "@") {
+			template.EmailAddresses = append(template.EmailAddresses, name)
+		} else {
+			template.DNSNames = append(template.DNSNames, name)
+		}
+	}
+
+	for _, ip := range splitOption(options["ip_sans"]) {
+		parsed := net.ParseIP(ip)
+		if parsed == nil {
+			return nil, fmt.Errorf("invalid ip_sans value %q", ip)
+		}
+		template.IPAddresses = append(template.IPAddresses, parsed)
+	}
+
+	for _, uri := range splitOption(options["uri_sans"]) {
+		parsed, err := url.Parse(uri)
+		if err != nil {
+			return nil, fmt.Errorf("invalid uri_sans value %q: %w", uri, err)
+		}
+		template.URIs = append(template.URIs, parsed)
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}), nil
+}
+
+// parsePrivateKey parses a PEM encoded private key in the PKCS #1, SEC 1 or
+// PKCS #8 format.
+func parsePrivateKey(privateKeyPEM []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("could not decode private key")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key")
+	}
+	return signer, nil
+}
+
+// privateKeyMatches returns true if the given key has the given type and size.
+// A size of zero matches the default size of the key type.
+func privateKeyMatches(key crypto.Signer, keyType string, keyBits int) bool {
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		if keyBits == 0 {
+			keyBits = 2048
+		}
+		return keyType == "rsa" && key.N.BitLen() == keyBits
+	case *ecdsa.PrivateKey:
+		curve, err := ellipticCurve(keyBits)
+		return keyType == "ec" && err == nil && key.Curve == curve
+	case ed25519.PrivateKey:
+		return keyType == "ed25519"
+	default:
+		return false
+	}
+}
+
+// ellipticCurve returns the curve for the given key size, which defaults to
+// P-256.
+func ellipticCurve(bits int) (elliptic.Curve, error) {
+	switch bits {
+	case 0, 256:
+		return elliptic.P256(), nil
+	case 384:
+		return elliptic.P384(), nil
+	case 521:
+		return elliptic.P521(), nil
+	default:
+		return nil, fmt.Errorf("unsupported private_key_bits %d for ec keys", bits)
+	}
+}
+
+// splitOption splits a comma separated engine option into its trimmed,
+// non-empty values.
+func splitOption(value string) []string {
+	var values []string
+	for v := range strings.SplitSeq(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// pkiTLSData returns the keys of a Kubernetes secret of the type
+// "kubernetes.io/tls" for the given secret data of the PKI secrets engine. The
+// "tls.crt" key contains the certificate followed by the CA chain (or the
+// issuing CA, when the chain is empty), the "tls.key" key the private key and
+// the "ca.crt" key the issuing CA.
+func pkiTLSData(data map[string][]byte) map[string][]byte {
+	chain := data["ca_chain"]
+	if len(chain) == 0 {
+		chain = data["issuing_ca"]
+	}
+
+	var certificate bytes.Buffer
+	certificate.Write(bytes.TrimRight(data["certificate"], "\n"))
+	certificate.WriteByte('\n')
+	if len(chain) > 0 {
+		certificate.Write(bytes.TrimRight(chain, "\n"))
+		certificate.WriteByte('\n')
+	}
+
+	return map[string][]byte{
+		corev1.TLSCertKey:       certificate.Bytes(),
+		corev1.TLSPrivateKeyKey: data["private_key"],
+		"ca.crt":                data["issuing_ca"],
+	}
+}
+
+// addPendingRevocation adds the previous certificate with the given serial
+// number to the pending revocations of the VaultSecret. The certificate is
+// revoked after the configured grace period.
+func addPendingRevocation(instance *ricobergerdev1alpha1.VaultSecret, serialNumber string) {
+	if serialNumber == "" {
+		return
+	}
+
+	for _, revocation := range instance.Status.PendingRevocations {
+		if revocation.SerialNumber == serialNumber {
+			return
+		}
+	}
+
+	instance.Status.PendingRevocations = append(instance.Status.PendingRevocations, ricobergerdev1alpha1.VaultSecretRevocation{
+		SerialNumber: serialNumber,
+		RevokeTime:   metav1.NewTime(time.Now().Add(revokeGracePeriod(instance))),
+	})
+}
+
+// revokeGracePeriod returns the grace period after which a previous
+// certificate is revoked.
+func revokeGracePeriod(instance *ricobergerdev1alpha1.VaultSecret) time.Duration {
+	if instance.Spec.RevokeGracePeriod == nil || instance.Spec.RevokeGracePeriod.Duration < 0 {
+		return 0
+	}
+	return instance.Spec.RevokeGracePeriod.Duration
+}
+
+// revokePendingCertificates revokes all pending certificates of the
+// VaultSecret, whose grace period is over. If the revocation of a certificate
+// fails, it is retried after the pkiRevokeRetryInterval. The returned boolean
+// is true when the pending revocations were changed, so that the status must
+// be updated.
+func revokePendingCertificates(instance *ricobergerdev1alpha1.VaultSecret, revoke func(serialNumber string) error) (bool, error) {
+	var (
+		changed bool
+		errs    []error
+		pending []ricobergerdev1alpha1.VaultSecretRevocation
+	)
+
+	for _, revocation := range instance.Status.PendingRevocations {
+		if !revocation.RevokeTime.After(time.Now()) {
+			changed = true
+			err := revoke(revocation.SerialNumber)
+			if err == nil {
+				continue
+			}
+
+			errs = append(errs, fmt.Errorf("could not revoke certificate %s: %w", revocation.SerialNumber, err))
+			revocation.RevokeTime = metav1.NewTime(time.Now().Add(pkiRevokeRetryInterval))
+		}
+
+		pending = append(pending, revocation)
+	}
+
+	if changed {
+		instance.Status.PendingRevocations = pending
+	}
+
+	return changed, errors.Join(errs...)
+}
+
+// pendingRevocationsRequeueAfter returns the duration until the next pending
+// revocation of the VaultSecret is due or zero if there are no pending
+// revocations. The returned duration is at least one second, because a zero
+// duration would not requeue the VaultSecret.
+func pendingRevocationsRequeueAfter(instance *ricobergerdev1alpha1.VaultSecret) time.Duration {
+	var next time.Duration
+	for i, revocation := range instance.Status.PendingRevocations {
+		if until := max(time.Until(revocation.RevokeTime.Time), time.Second); i == 0 || until < next {
+			next = until
+		}
+	}
+	return next
+}
+
+// minRequeueAfter returns the smaller of the two durations, where zero means
+// that no requeue is required.
+func minRequeueAfter(a, b time.Duration) time.Duration {
+	if a <= 0 || (b > 0 && b < a) {
+		return b
+	}
+	return a
+}
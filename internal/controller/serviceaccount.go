@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/vault-secrets-operator/api/v1alpha1"
+	"github.com/ricoberger/vault-secrets-operator/internal/vault"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// defaultServiceAccountName is the name of the service account, which is
+	// used for a VaultSecret without the serviceAccountName property.
+	defaultServiceAccountName = "default"
+
+	// serviceAccountTokenExpiration is the lifetime of the requested service
+	// account tokens. The tokens are only used for the login to Vault, so that
+	// we request the minimum lifetime allowed by the Kubernetes API server.
+	serviceAccountTokenExpiration = 10 * time.Minute
+)
+
+// serviceAccountName returns the name of the service account in the namespace
+// of the VaultSecret, which is used to authenticate against Vault.
+func serviceAccountName(instance *ricobergerdev1alpha1.VaultSecret) string {
+	if instance.Spec.ServiceAccountName != "" {
+		return instance.Spec.ServiceAccountName
+	}
+	return defaultServiceAccountName
+}
+
+// serviceAccountToken returns a function, which requests a new token for the
+// service account of the VaultSecret via the TokenRequest API. The returned
+// function is also used when the Vault client requests a new Vault token, so
+// that it must not be canceled together with the context of the reconciliation.
+func (r *VaultSecretReconciler) serviceAccountToken(ctx context.Context, instance *ricobergerdev1alpha1.VaultSecret) vault.ServiceAccountToken {
+	ctx = context.WithoutCancel(ctx)
+	namespace := instance.Namespace
+	name := serviceAccountName(instance)
+
+	return func() (string, error) {
+		expirationSeconds := int64(serviceAccountTokenExpiration.Seconds())
+		tokenRequest := &authenticationv1.TokenRequest{
+			Spec: authenticationv1.TokenRequestSpec{
+				Audiences:         vault.ServiceAccountTokenAudiences,
+				ExpirationSeconds: &expirationSeconds,
+			},
+		}
+
+		serviceAccount := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+		if err := r.SubResource("token").Create(ctx, serviceAccount, tokenRequest); err != nil {
+			return "", fmt.Errorf("could not request token for service account %s/%s: %w", namespace, name, err)
+		}
+
+		return tokenRequest.Status.Token, nil
+	}
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/vault-secrets-operator/api/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// TestServiceAccountToken verifies that a token is requested for the service
+// account of the VaultSecret and that the default service account is used, when
+// the serviceAccountName property is not set.
+func TestServiceAccountToken(t *testing.T) {
+	r := &VaultSecretReconciler{
+		Client: fake.NewClientBuilder().WithObjects(
+			&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "team-a"}},
+			&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-a"}},
+		).Build(),
+	}
+
+	for _, tc := range []struct {
+		name               string
+		serviceAccountName string
+		namespace          string
+		wantName           string
+		wantErr            bool
+	}{
+		{name: "default service account", namespace: "team-a", wantName: "default"},
+		{name: "service account from spec", serviceAccountName: "app", namespace: "team-a", wantName: "app"},
+		{name: "service account in other namespace", serviceAccountName: "app", namespace: "team-b", wantName: "app", wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			instance := &ricobergerdev1alpha1.VaultSecret{}
+			instance.Namespace = tc.namespace
+			instance.Spec.ServiceAccountName = tc.serviceAccountName
+
+			if got := serviceAccountName(instance); got != tc.wantName {
+				t.Errorf("serviceAccountName = %q, want %q", got, tc.wantName)
+			}
+
+			token, err := r.serviceAccountToken(context.Background(), instance)()
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if token == "" {
+				t.Error("expected a token")
+			}
+		})
+	}
+}
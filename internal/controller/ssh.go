@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// sshKeyPair returns the private key and the public key in the authorized_keys
+// format, which should be signed by the SSH secrets engine. If the given Secret
+// data already contains a private key, this key is reused, so that the key
+// pair does not change when the certificate is renewed. Otherwise a new
+// Ed25519 key pair is generated.
+func sshKeyPair(data map[string][]byte) ([]byte, []byte, error) {
+	if privateKeyPEM, ok := data["private_key"]; ok {
+		if privateKey, err := ssh.ParseRawPrivateKey(privateKeyPEM); err == nil {
+			if signer, err := ssh.NewSignerFromKey(privateKey); err == nil {
+				return privateKeyPEM, ssh.MarshalAuthorizedKey(signer.PublicKey()), nil
+			}
+		}
+	}
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	block, err := ssh.MarshalPrivateKey(privateKey, "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pem.EncodeToMemory(block), ssh.MarshalAuthorizedKey(sshPublicKey), nil
+}
+
+// sshCertificateRenewAfter parses the signed SSH certificate from the
+// "signed_key" field of the provided Secret data and returns the duration
+// after which the certificate must be renewed, based on its ValidBefore field.
+// Like for leases, the renew window is limited to a third of the validity
+// period, so that short lived certificates are not renewed on every reconcile.
+// The returned boolean is false when no certificate could be found.
+func sshCertificateRenewAfter(data map[string][]byte, renew time.Duration) (time.Time, time.Duration, bool) {
+	signedKey, ok := data["signed_key"]
+	if !ok {
+		return time.Time{}, 0, false
+	}
+
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey(signedKey)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+
+	cert, ok := publicKey.(*ssh.Certificate)
+	if !ok || cert.ValidBefore == ssh.CertTimeInfinity {
+		return time.Time{}, 0, false
+	}
+
+	//nolint:gosec
+	validAfter := time.Unix(int64(cert.ValidAfter), 0)
+	//nolint:gosec
+	validBefore := time.Unix(int64(cert.ValidBefore), 0)
+
+	if maxRenew := validBefore.Sub(validAfter) / 3; renew > maxRenew {
+		renew = maxRenew
+	}
+
+	return validBefore, time.Until(validBefore) - renew, true
+}
@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// testSSHCertificate signs the given public key with a new CA key and returns
+// the certificate in the authorized_keys format.
+func testSSHCertificate(t *testing.T, publicKey []byte, validAfter, validBefore time.Time) []byte {
+	t.Helper()
+
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(publicKey)
+	if err != nil {
+		t.Fatalf("failed to parse public key: %v", err)
+	}
+
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ca key: %v", err)
+	}
+	caSigner, err := ssh.NewSignerFromKey(caKey)
+	if err != nil {
+		t.Fatalf("failed to create ca signer: %v", err)
+	}
+
+	cert := &ssh.Certificate{
+		Key:             pub,
+		CertType:        ssh.UserCert,
+		ValidPrincipals: []string{"ubuntu"},
+		ValidAfter:      uint64(validAfter.Unix()),
+		ValidBefore:     uint64(validBefore.Unix()),
+	}
+	if err := cert.SignCert(rand.Reader, caSigner); err != nil {
+		t.Fatalf("failed to sign certificate: %v", err)
+	}
+
+	return ssh.MarshalAuthorizedKey(cert)
+}
+
+// TestSSHKeyPair verifies that a new key pair is generated when the Secret does
+// not contain a private key and that an existing private key is reused.
+func TestSSHKeyPair(t *testing.T) {
+	privateKey, publicKey, err := sshKeyPair(map[string][]byte{})
+	if err != nil {
+		t.Fatalf("sshKeyPair returned an error: %v", err)
+	}
+	if _, _, _, _, err := ssh.ParseAuthorizedKey(publicKey); err != nil {
+		t.Fatalf("public key is not in the authorized_keys format: %v", err)
+	}
+
+	reusedPrivateKey, reusedPublicKey, err := sshKeyPair(map[string][]byte{"private_key": privateKey})
+	if err != nil {
+		t.Fatalf("sshKeyPair returned an error: %v", err)
+	}
+	if string(reusedPrivateKey) != string(privateKey) {
+		t.Error("expected the existing private key to be reused")
+	}
+	if string(reusedPublicKey) != string(publicKey) {
+		t.Errorf("public key = %q, want %q", reusedPublicKey, publicKey)
+	}
+}
+
+// TestSSHCertificateRenewAfter verifies that the renewal of a signed SSH
+// certificate is driven by its ValidBefore field.
+func TestSSHCertificateRenewAfter(t *testing.T) {
+	_, publicKey, err := sshKeyPair(map[string][]byte{})
+	if err != nil {
+		t.Fatalf("sshKeyPair returned an error: %v", err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+
+	t.Run("renew window is applied", func(t *testing.T) {
+		validBefore := now.Add(24 * time.Hour)
+		data := map[string][]byte{"signed_key": testSSHCertificate(t, publicKey, now, validBefore)}
+
+		got, renewAfter, ok := sshCertificateRenewAfter(data, time.Hour)
+		if !ok {
+			t.Fatal("expected a certificate to be found")
+		}
+		if !got.Equal(validBefore) {
+			t.Errorf("validBefore = %s, want %s", got, validBefore)
+		}
+		if want := 23 * time.Hour; renewAfter > want || renewAfter < want-time.Minute {
+			t.Errorf("renewAfter = %s, want about %s", renewAfter, want)
+		}
+	})
+
+	t.Run("renew window is limited for short lived certificates", func(t *testing.T) {
+		data := map[string][]byte{"signed_key": testSSHCertificate(t, publicKey, now, now.Add(30*time.Minute))}
+
+		_, renewAfter, ok := sshCertificateRenewAfter(data, time.Hour)
+		if !ok {
+			t.Fatal("expected a certificate to be found")
+		}
+		if want := 20 * time.Minute; renewAfter > want || renewAfter < want-time.Minute {
+			t.Errorf("renewAfter = %s, want about %s", renewAfter, want)
+		}
+	})
+
+	t.Run("no certificate present", func(t *testing.T) {
+		if _, _, ok := sshCertificateRenewAfter(map[string][]byte{"public_key": publicKey}, time.Hour); ok {
+			t.Error("expected no certificate to be found")
+		}
+	})
+}
@@ -6,25 +6,33 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
+	"maps"
 	"os"
 	"reflect"
+	"slices"
 	"text/template"
 	"time"
 
 	ricobergerdev1alpha1 "github.com/ricoberger/vault-secrets-operator/api/v1alpha1"
+	"github.com/ricoberger/vault-secrets-operator/internal/tracing"
 	"github.com/ricoberger/vault-secrets-operator/internal/validators"
 	"github.com/ricoberger/vault-secrets-operator/internal/vault"
 
 	"github.com/Masterminds/sprig/v3"
 	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -43,15 +51,53 @@ const (
 	conditionReasonUpdateFailed    = "UpdateFailed"
 	conditionReasonMergeFailed     = "MergeFailed"
 	conditionReasonInvalidResource = "InvalidResource"
+	conditionReasonRevokeFailed    = "RevokeFailed"
+	conditionReasonLeaseRenewed    = "LeaseRenewed"
+
+	eventReasonUnchanged          = "Unchanged"
+	eventReasonCertificateRenewed = "CertificateRenewed"
+	eventReasonLeaseRevoked       = "LeaseRevoked"
+	eventReasonRevokeSkipped      = "RevokeSkipped"
+	eventActionReconcile          = "Reconcile"
+	eventActionRevoke             = "Revoke"
+
+	// eventMessageUnchanged is the message of the event for a reconciliation
+	// without changes. It doesn't contain any details, so that the events of
+	// all skipped reconciliations of a VaultSecret are aggregated into a
+	// single event by the events API.
+	eventMessageUnchanged = "Secret was not changed"
 
 	vaultsecretsFinalizer = "vaultsecrets.ricoberger.de/finalizer"
+
+	// leaseIDAnnotation is the annotation of a Secret, which contains the ID of
+	// the lease of the credentials in the Secret. It is used to find the lease
+	// of the credentials, when the status of the VaultSecret could not be
+	// updated after the Secret was written.
+	leaseIDAnnotation = "vaultsecrets.ricoberger.de/lease-id"
+
+	// revokeTimeout is the time after the deletion of a VaultSecret, after
+	// which a failing revocation is given up and the finalizer is removed, so
+	// that an unreachable Vault doesn't block the deletion of the VaultSecret
+	// and its namespace forever.
+	revokeTimeout = 15 * time.Minute
 )
 
 const (
-	kvEngine  = "kv"
-	pkiEngine = "pki"
+	kvEngine       = "kv"
+	pkiEngine      = "pki"
+	databaseEngine = "database"
+	awsEngine      = "aws"
+	transitEngine  = "transit"
+	sshEngine      = "ssh"
 )
 
+// tracer creates the spans for the reconciliations. Every reconciliation is
+// recorded as a trace with a "Reconcile" root span, which contains the spans
+// for the requests against Vault and the Kubernetes API and for the rendering
+// of the templates. It uses the global tracer provider, which is a no-op
+// provider until tracing is configured.
+var tracer = otel.Tracer("github.com/ricoberger/vault-secrets-operator/internal/controller")
+
 var (
 	vaultSecretsReconciliationsTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -67,6 +113,35 @@ var (
 		},
 		[]string{"namespace", "name"},
 	)
+	vaultSecretsReconciliationFailuresTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vaultsecrets_reconciliation_failures_total",
+			Help: "Total number of failed reconciliations by reason",
+		},
+		[]string{"namespace", "name", "reason"},
+	)
+	vaultSecretsReconciliationDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "vaultsecrets_reconciliation_duration_seconds",
+			Help:    "Duration of reconciliations in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"namespace", "name"},
+	)
+	vaultSecretsCertificateExpirationTimestampSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "vaultsecrets_certificate_expiration_timestamp_seconds",
+			Help: "Unix timestamp when the certificate of the secret expires",
+		},
+		[]string{"namespace", "name"},
+	)
+	vaultSecretsCertificateRenewalTimestampSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "vaultsecrets_certificate_renewal_timestamp_seconds",
+			Help: "Unix timestamp when the certificate of the secret is scheduled for renewal",
+		},
+		[]string{"namespace", "name"},
+	)
 )
 
 // VaultSecretReconciler reconciles a VaultSecret object
@@ -78,12 +153,33 @@ type VaultSecretReconciler struct {
 	// configured (which forces a cluster-wide cache); otherwise it is nil and
 	// behavior is unchanged.
 	NamespaceFilter *NamespaceFilter
+	// MaxConcurrentReconciles is the maximum number of VaultSecrets which can
+	// be reconciled in parallel. If it is not set, the default value from the
+	// controller-runtime (1) is used.
+	MaxConcurrentReconciles int
+	// ClientPool caches the Vault clients for VaultSecrets with the vaultRole
+	// property. If it is nil, a new client is created for every
+	// reconciliation.
+	ClientPool *vault.ClientPool
+	// OmitMetricsObjectLabels sets the namespace and name labels of the
+	// metrics to an empty value, so that the number of series does not grow
+	// with the number of VaultSecrets. The metrics which are only meaningful
+	// per VaultSecret (e.g. the reconciliation status) are not exported then.
+	OmitMetricsObjectLabels bool
+	// Recorder emits the Kubernetes Events for the VaultSecrets, so that the
+	// result of a reconciliation is shown by "kubectl describe". If it is nil,
+	// no events are emitted.
+	Recorder events.EventRecorder
 }
 
 func init() {
 	// Register custom metrics with the global prometheus registry
 	metrics.Registry.MustRegister(vaultSecretsReconciliationsTotal)
 	metrics.Registry.MustRegister(vaultSecretsReconciliationStatus)
+	metrics.Registry.MustRegister(vaultSecretsReconciliationFailuresTotal)
+	metrics.Registry.MustRegister(vaultSecretsReconciliationDurationSeconds)
+	metrics.Registry.MustRegister(vaultSecretsCertificateExpirationTimestampSeconds)
+	metrics.Registry.MustRegister(vaultSecretsCertificateRenewalTimestampSeconds)
 }
 
 // +kubebuilder:rbac:groups=ricoberger.de,resources=vaultsecrets,verbs=get;list;watch;create;update;patch;delete
@@ -93,16 +189,34 @@ func init() {
 // +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=serviceaccounts/token,verbs=create
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.7.0/pkg/reconcile
+//
+// The reconciliation is recorded as span, when tracing is configured.
+func (r *VaultSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, err error) {
+	ctx, span := tracer.Start(ctx, "Reconcile", trace.WithAttributes(
+		attribute.String("vaultsecret.namespace", req.Namespace),
+		attribute.String("vaultsecret.name", req.Name),
+	))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	return r.reconcile(ctx, req)
+}
+
+// reconcile reconciles the VaultSecret of the given request. The spans for the
+// requests against Vault and the Kubernetes API are children of the span of
+// the reconciliation from the given context.
 // nolint:gocyclo
-func (r *VaultSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *VaultSecretReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := logr.FromContext(ctx)
+	start := time.Now()
 
 	// Set reconciliation if the vault-secret does not specify a version.
 	reconcileResult := ctrl.Result{}
@@ -134,9 +248,26 @@ func (r *VaultSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	// deleted.
 	isVaultSecretMarkedToBeDeleted := instance.GetDeletionTimestamp() != nil
 	if isVaultSecretMarkedToBeDeleted {
-		vaultSecretsReconciliationsTotal.DeleteLabelValues(instance.Namespace, instance.Name, string(metav1.ConditionTrue))
-		vaultSecretsReconciliationsTotal.DeleteLabelValues(instance.Namespace, instance.Name, string(metav1.ConditionFalse))
-		vaultSecretsReconciliationStatus.DeleteLabelValues(instance.Namespace, instance.Name)
+		if controllerutil.ContainsFinalizer(instance, vaultsecretsFinalizer) {
+			// Revoke the lease or certificate which was issued for the
+			// VaultSecret, so that the credentials can not be used anymore
+			// after the VaultSecret was deleted. If the revocation fails we
+			// keep the finalizer and retry the deletion until the revoke
+			// timeout is reached.
+			err = r.revoke(ctx, instance)
+			if err != nil {
+				if time.Since(instance.GetDeletionTimestamp().Time) < revokeTimeout {
+					log.Error(err, "Could not revoke lease.")
+					r.updateConditions(ctx, instance, conditionReasonRevokeFailed, err.Error(), metav1.ConditionFalse)
+					return ctrl.Result{}, err
+				}
+
+				log.Error(err, "Could not revoke lease, remove finalizer because the revoke timeout is reached.")
+				r.recordEvent(instance, corev1.EventTypeWarning, eventReasonRevokeSkipped, eventActionRevoke, "Revocation was given up after %s: %s", revokeTimeout, err.Error())
+			}
+		}
+
+		r.deleteMetrics(instance)
 
 		if controllerutil.ContainsFinalizer(instance, vaultsecretsFinalizer) {
 			// Remove the vaultsecretsFinalizer. Once the finalizer is removed
@@ -166,6 +297,11 @@ func (r *VaultSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		}
 	}
 
+	// Record the duration of the reconciliation. Deleted VaultSecrets are
+	// handled above, so that their metrics are not recreated after they were
+	// removed.
+	defer r.observeReconciliationDuration(instance, start)
+
 	// Add the vaultsecretsFinalizer to the VaultSecret. The finilizer is needed
 	// so that we can remove the metrics for a delete secret.
 	if !controllerutil.ContainsFinalizer(instance, vaultsecretsFinalizer) {
@@ -179,37 +315,34 @@ func (r *VaultSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	}
 
 	// Get secret from Vault.
-	// If the VaultSecret contains the vaulRole property we are creating a new
-	// client with the specified Vault Role to get the secret.
-	// When the property isn't set we are using the shared client. It is also
-	// possible that the shared client is nil, so that we have to check for this
-	// first. This could happen since we do not return an error when we
-	// initializing the client during start up, to not require a default Vault
-	// Role.
 	var data map[string][]byte
 
 	var secretsPaths []secretPath
 
-	var vaultClient *vault.Client
+	// previousSerialNumber is the serial number of the certificate, which was
+	// replaced by a new certificate and which should be revoked once the
+	// Secret was updated.
+	var previousSerialNumber string
 
-	if instance.Spec.VaultRole != "" {
-		log.WithValues("vaultRole", instance.Spec.VaultRole).Info("Create client to get secret from Vault")
-		vaultClient, err = vault.CreateClient(instance.Spec.VaultRole)
-		if err != nil {
-			// Error creating the Vault client - requeue the request.
-			r.updateConditions(ctx, instance, conditionReasonFetchFailed, err.Error(), metav1.ConditionFalse)
-			return ctrl.Result{}, err
-		}
-	} else {
-		log.Info("Use shared client to get secret from Vault")
-		if vault.SharedClient == nil {
-			err = fmt.Errorf("shared client not initialized and vaultRole property missing")
-			log.Error(err, "Could not get secret from Vault")
-			r.updateConditions(ctx, instance, conditionReasonFetchFailed, err.Error(), metav1.ConditionFalse)
-			return ctrl.Result{}, err
-		}
+	// certExpiration is the expiration date of a new certificate, which is
+	// exported as metric once the Secret was created or updated.
+	var certExpiration *time.Time
+
+	// certificateRenewed is true, when a new certificate replaces the
+	// certificate of the existing Secret.
+	var certificateRenewed bool
+
+	// supersededLeaseIDs are the IDs of the leases, which are still valid, but
+	// whose credentials are replaced by new credentials. The leases are
+	// revoked once the Secret was created or updated.
+	var supersededLeaseIDs []string
 
-		vaultClient = vault.SharedClient
+	vaultClient, err := r.getVaultClient(ctx, instance)
+	if err != nil {
+		// Error creating the Vault client - requeue the request.
+		log.Error(err, "Could not get secret from Vault")
+		r.updateConditions(ctx, instance, conditionReasonFetchFailed, err.Error(), metav1.ConditionFalse)
+		return ctrl.Result{}, err
 	}
 
 	// If the `VAULT_RESTRICT_NAMESPACE` environment variable is set to `true`
@@ -229,6 +362,13 @@ func (r *VaultSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
+	// Validate that the keystores are only requested for certificates.
+	if err := validators.ValidateKeystores(instance); err != nil {
+		log.Error(err, "Resource validation failed")
+		r.updateConditions(ctx, instance, conditionReasonInvalidResource, err.Error(), metav1.ConditionFalse)
+		return ctrl.Result{}, err
+	}
+
 	switch instance.Spec.SecretEngine {
 	case "", kvEngine:
 		// Build the ordered list of Vault paths. The optional 'path' field is
@@ -245,13 +385,13 @@ func (r *VaultSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		secretsPaths = make([]secretPath, 0, len(paths))
 
 		for _, path := range paths {
-			pathData, err := vaultClient.GetSecret(path, instance.Spec.Keys, instance.Spec.Version, instance.Spec.IsBinary, instance.Spec.VaultNamespace)
+			pathData, err := vaultClient.GetSecret(ctx, path, instance.Spec.Keys, instance.Spec.Version, instance.Spec.IsBinary, instance.Spec.VaultNamespace)
 			if err != nil {
 				// Error while getting the secret from Vault - requeue the
 				// request. A failure for any single path fails the whole
 				// reconciliation, so that we never create a partial secret.
 				log.Error(err, "Could not get secret from vault", "path", path)
-				r.updateConditions(ctx, instance, conditionReasonFetchFailed, err.Error(), metav1.ConditionFalse)
+				r.updateConditions(ctx, instance, conditionReasonFetchFailed, fetchFailedMessage(path, err), metav1.ConditionFalse)
 				return ctrl.Result{}, err
 			}
 
@@ -285,7 +425,19 @@ func (r *VaultSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			return ctrl.Result{}, err
 		}
 
-		if err == nil {
+		// Revoke the previous certificates, which were replaced by a new
+		// certificate and whose grace period is over. A failed revocation
+		// should not block the reconciliation, so that we only log the error
+		// and retry the revocation later.
+		revocationsChanged, err := revokePendingCertificates(instance, func(serialNumber string) error {
+			log.Info("Revoke previous certificate", "serialNumber", serialNumber)
+			return vaultClient.RevokeCertificate(ctx, instance.Spec.Path, serialNumber, instance.Spec.VaultNamespace)
+		})
+		if err != nil {
+			log.Error(err, "Could not revoke previous certificate")
+		}
+
+		if existing.Data != nil {
 			if certExpiration, ok := certificateExpiration(existing.Data); ok {
 				renewAfter := time.Until(certExpiration) - vaultClient.GetPKIRenew()
 				if renewAfter > 0 {
@@ -296,23 +448,108 @@ func (r *VaultSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 					// before the certificate needs to be renewed.
 					log.Info("Skip updating a Secret cause the certificate is still valid", "Secret.Namespace", instance.Namespace, "Secret.Name", instance.Name)
 					log.Info(fmt.Sprintf("Certificate will expire on %s and will be renewed on %s", certExpiration.String(), time.Now().Add(renewAfter).String()))
-					reconcileResult.RequeueAfter = renewAfter
-					vaultSecretsReconciliationsTotal.WithLabelValues(instance.Namespace, instance.Name, string(metav1.ConditionTrue)).Inc()
-					vaultSecretsReconciliationStatus.WithLabelValues(instance.Namespace, instance.Name).Set(1)
+					r.setCertificateMetrics(instance, certExpiration, vaultClient.GetPKIRenew())
+					reconcileResult.RequeueAfter = minRequeueAfter(renewAfter, pendingRevocationsRequeueAfter(instance))
+					if revocationsChanged {
+						if err := r.Status().Update(ctx, instance); err != nil {
+							log.Error(err, "Could not update status")
+						}
+					}
+					r.observeReconciliation(instance, metav1.ConditionTrue)
+					r.recordEvent(instance, corev1.EventTypeNormal, eventReasonUnchanged, eventActionReconcile, eventMessageUnchanged)
 					return reconcileResult, nil
 				}
 			}
 		}
 
+		// In the "sign" mode the private key is generated by the operator (or
+		// reused from the existing Secret) and only a CSR is sent to Vault, so
+		// that the private key never leaves the cluster. In the default
+		// "issue" mode Vault generates the private key.
 		var expiration *time.Time
-		data, expiration, err = vaultClient.GetCertificate(instance.Spec.Path, instance.Spec.Role, instance.Spec.EngineOptions)
-		if err != nil {
-			log.Error(err, "Could not get certificate from vault")
-			r.updateConditions(ctx, instance, conditionReasonFetchFailed, err.Error(), metav1.ConditionFalse)
-			return ctrl.Result{}, err
+		options := pkiVaultOptions(instance.Spec.EngineOptions)
+		if pkiMode(instance.Spec.EngineOptions) == pkiModeSign {
+			privateKey, privateKeyPEM, privateKeyType, err := pkiPrivateKey(existing.Data, instance.Spec.EngineOptions)
+			if err != nil {
+				log.Error(err, "Could not generate private key")
+				r.updateConditions(ctx, instance, conditionReasonCreateFailed, err.Error(), metav1.ConditionFalse)
+				return ctrl.Result{}, err
+			}
+
+			csr, err := pkiCertificateRequest(privateKey, instance.Spec.EngineOptions)
+			if err != nil {
+				log.Error(err, "Could not create certificate signing request")
+				r.updateConditions(ctx, instance, conditionReasonCreateFailed, err.Error(), metav1.ConditionFalse)
+				return ctrl.Result{}, err
+			}
+
+			data, expiration, err = vaultClient.SignCertificate(ctx, instance.Spec.Path, instance.Spec.Role, csr, options, instance.Spec.VaultNamespace)
+			if err != nil {
+				log.Error(err, "Could not sign certificate with vault")
+				r.updateConditions(ctx, instance, conditionReasonFetchFailed, fetchFailedMessage(instance.Spec.Path, err), metav1.ConditionFalse)
+				return ctrl.Result{}, err
+			}
+
+			data["private_key"] = privateKeyPEM
+			data["private_key_type"] = []byte(privateKeyType)
+		} else {
+			data, expiration, err = vaultClient.GetCertificate(ctx, instance.Spec.Path, instance.Spec.Role, options, instance.Spec.VaultNamespace)
+			if err != nil {
+				log.Error(err, "Could not get certificate from vault")
+				r.updateConditions(ctx, instance, conditionReasonFetchFailed, fetchFailedMessage(instance.Spec.Path, err), metav1.ConditionFalse)
+				return ctrl.Result{}, err
+			}
+		}
+
+		// Add the keystores for JVM based workloads, which can not use the
+		// PEM encoded certificate and private key directly.
+		if instance.Spec.Keystores != nil {
+			password, err := r.keystorePassword(ctx, instance)
+			if err != nil {
+				log.Error(err, "Could not get keystore password")
+				r.updateConditions(ctx, instance, conditionReasonFetchFailed, err.Error(), metav1.ConditionFalse)
+				return ctrl.Result{}, err
+			}
+
+			keystoresData, err := keystores(data, password)
+			if err != nil {
+				log.Error(err, "Could not create keystores")
+				r.updateConditions(ctx, instance, conditionReasonCreateFailed, err.Error(), metav1.ConditionFalse)
+				return ctrl.Result{}, err
+			}
+			maps.Copy(data, keystoresData)
+		}
+
+		// Add the keys which are expected in a Secret of the type
+		// "kubernetes.io/tls" (e.g. by ingress controllers), so that users do
+		// not have to rename the fields via templates. When templates are
+		// used, the keys are only available in the templates.
+		if instance.Spec.Type == corev1.SecretTypeTLS && !instance.Spec.SkipTLSKeyMapping {
+			maps.Copy(data, pkiTLSData(data))
+		}
+
+		// Save the serial number of the certificate in the status of the
+		// VaultSecret, so that the certificate can be revoked when the
+		// VaultSecret is deleted.
+		instance.Status.SerialNumber = string(data["serial_number"])
+
+		// Remember the serial number of the replaced certificate, so that it
+		// can be revoked after the grace period, when the Secret was updated
+		// with the new certificate.
+		if instance.Spec.RevokePreviousCertificate {
+			if serialNumber := string(existing.Data["serial_number"]); serialNumber != instance.Status.SerialNumber {
+				previousSerialNumber = serialNumber
+			}
+		}
+
+		// Remember if an existing certificate was replaced, so that an event
+		// is emitted once the Secret was updated.
+		if serialNumber := string(existing.Data["serial_number"]); serialNumber != "" && serialNumber != instance.Status.SerialNumber {
+			certificateRenewed = true
 		}
 
 		// Requeue before expiration
+		certExpiration = expiration
 		log.Info(fmt.Sprintf("Certificate will expire on %s", expiration.String()))
 		ra := time.Until(*expiration) - vaultClient.GetPKIRenew()
 		if ra <= 0 {
@@ -321,10 +558,221 @@ func (r *VaultSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			reconcileResult.RequeueAfter = ra
 			log.Info(fmt.Sprintf("Certificate will be renewed on %s", time.Now().Add(ra).String()))
 		}
+		reconcileResult.RequeueAfter = minRequeueAfter(reconcileResult.RequeueAfter, pendingRevocationsRequeueAfter(instance))
+		if previousSerialNumber != "" {
+			reconcileResult.RequeueAfter = minRequeueAfter(reconcileResult.RequeueAfter, max(revokeGracePeriod(instance), time.Second))
+		}
+
+	case transitEngine:
+		if err := validators.ValidateTransit(instance); err != nil {
+			log.Error(err, "Resource validation failed")
+			r.updateConditions(ctx, instance, conditionReasonInvalidResource, err.Error(), metav1.ConditionFalse)
+			return ctrl.Result{}, err
+		}
+
+		data, err = vaultClient.DecryptCiphertexts(ctx, instance.Spec.Path, instance.Spec.TransitKey, instance.Spec.Ciphertexts, instance.Spec.Keys, instance.Spec.IsBinary, instance.Spec.EngineOptions, instance.Spec.VaultNamespace)
+		if err != nil {
+			log.Error(err, "Could not decrypt ciphertexts with vault")
+			r.updateConditions(ctx, instance, conditionReasonFetchFailed, fetchFailedMessage(instance.Spec.Path, err), metav1.ConditionFalse)
+			return ctrl.Result{}, err
+		}
+
+	case sshEngine:
+		if err := validators.ValidateSSH(instance); err != nil {
+			log.Error(err, "Resource validation failed")
+			r.updateConditions(ctx, instance, conditionReasonInvalidResource, err.Error(), metav1.ConditionFalse)
+			return ctrl.Result{}, err
+		}
+
+		// Like for the PKI engine, we only sign a new certificate when the
+		// Secret does not exist yet or when the existing certificate expires
+		// within the renew window (VAULT_PKI_RENEW).
+		existing := &corev1.Secret{}
+		err = r.Get(ctx, types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, existing)
+		if err != nil && !errors.IsNotFound(err) {
+			log.Error(err, "Could not get secret")
+			r.updateConditions(ctx, instance, conditionReasonFetchFailed, err.Error(), metav1.ConditionFalse)
+			return ctrl.Result{}, err
+		}
+
+		if err == nil {
+			if validBefore, renewAfter, ok := sshCertificateRenewAfter(existing.Data, vaultClient.GetPKIRenew()); ok && renewAfter > 0 {
+				log.Info("Skip updating a Secret cause the certificate is still valid", "Secret.Namespace", instance.Namespace, "Secret.Name", instance.Name)
+				log.Info(fmt.Sprintf("Certificate will expire on %s and will be renewed on %s", validBefore.String(), time.Now().Add(renewAfter).String()))
+				reconcileResult.RequeueAfter = renewAfter
+				r.observeReconciliation(instance, metav1.ConditionTrue)
+				r.recordEvent(instance, corev1.EventTypeNormal, eventReasonUnchanged, eventActionReconcile, eventMessageUnchanged)
+				return reconcileResult, nil
+			}
+		}
+
+		// The public key, which should be signed, can be provided via the
+		// "public_key" engine option. If it is not provided, we use the key
+		// pair from the existing Secret or generate a new one. The private key
+		// is then stored next to the signed certificate in the Secret.
+		options := make(map[string]string, len(instance.Spec.EngineOptions))
+		for k, v := range instance.Spec.EngineOptions {
+			options[k] = v
+		}
+
+		publicKey, ok := options["public_key"]
+		delete(options, "public_key")
+
+		var privateKey []byte
+		if !ok {
+			var publicKeyBytes []byte
+			privateKey, publicKeyBytes, err = sshKeyPair(existing.Data)
+			if err != nil {
+				log.Error(err, "Could not generate ssh key pair")
+				r.updateConditions(ctx, instance, conditionReasonCreateFailed, err.Error(), metav1.ConditionFalse)
+				return ctrl.Result{}, err
+			}
+			publicKey = string(publicKeyBytes)
+		}
+
+		data, err = vaultClient.SignSSHKey(ctx, instance.Spec.Path, instance.Spec.Role, publicKey, options, instance.Spec.VaultNamespace)
+		if err != nil {
+			log.Error(err, "Could not sign ssh key with vault")
+			r.updateConditions(ctx, instance, conditionReasonFetchFailed, fetchFailedMessage(instance.Spec.Path, err), metav1.ConditionFalse)
+			return ctrl.Result{}, err
+		}
+
+		data["public_key"] = []byte(publicKey)
+		if privateKey != nil {
+			data["private_key"] = privateKey
+		}
+
+		// Requeue before expiration
+		if validBefore, ra, ok := sshCertificateRenewAfter(data, vaultClient.GetPKIRenew()); ok {
+			log.Info(fmt.Sprintf("Certificate will expire on %s", validBefore.String()))
+			if ra <= 0 {
+				reconcileResult.RequeueAfter = 0 * time.Second
+			} else {
+				reconcileResult.RequeueAfter = ra
+				log.Info(fmt.Sprintf("Certificate will be renewed on %s", time.Now().Add(ra).String()))
+			}
+		}
+
+	case databaseEngine, awsEngine:
+		if err := validators.ValidateDatabase(instance); err != nil {
+			log.Error(err, "Resource validation failed")
+			r.updateConditions(ctx, instance, conditionReasonInvalidResource, err.Error(), metav1.ConditionFalse)
+			return ctrl.Result{}, err
+		}
+
+		if err := validators.ValidateAWS(instance); err != nil {
+			log.Error(err, "Resource validation failed")
+			r.updateConditions(ctx, instance, conditionReasonInvalidResource, err.Error(), metav1.ConditionFalse)
+			return ctrl.Result{}, err
+		}
+
+		// Database and AWS credentials are dynamic, so that every request
+		// against Vault returns new credentials. To avoid updating the
+		// Kubernetes Secret on every reconcile, we only request new credentials
+		// when the Secret does not exist yet or when the lease of the current
+		// credentials expires within the renew window (VAULT_LEASE_RENEW) and
+		// can not be renewed.
+		existing := &corev1.Secret{}
+		err = r.Get(ctx, types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, existing)
+		if err != nil && !errors.IsNotFound(err) {
+			log.Error(err, "Could not get secret")
+			r.updateConditions(ctx, instance, conditionReasonFetchFailed, err.Error(), metav1.ConditionFalse)
+			return ctrl.Result{}, err
+		}
+
+		// The lease from the status is only renewed or kept, when the spec of
+		// the VaultSecret didn't change since the credentials were requested
+		// and when the credentials in the Secret belong to the lease. A
+		// missing observed generation or lease annotation (e.g. for Secrets
+		// created by an older version of the operator) is not treated as
+		// change.
+		secretLeaseID := existing.Annotations[leaseIDAnnotation]
+		leaseObserved := instance.Status.Lease != nil &&
+			(secretLeaseID == "" || secretLeaseID == instance.Status.Lease.ID) &&
+			(instance.Status.ObservedGeneration == 0 || instance.Status.ObservedGeneration == instance.GetGeneration())
+
+		if err == nil && !leaseObserved {
+			if secretLeaseID != "" {
+				supersededLeaseIDs = append(supersededLeaseIDs, secretLeaseID)
+			}
+			log.Info("Request new credentials, because the spec or the lease of the Secret changed", "leaseIDs", supersededLeaseIDs)
+		}
+
+		if err == nil && leaseObserved {
+			renewAfter := leaseRenewAfter(instance.Status.Lease, vaultClient.GetLeaseRenew())
+
+			// If the lease is renewable we try to renew it instead of requesting
+			// new credentials, so that the existing Secret is kept and workloads
+			// can continue to use the credentials. New credentials are only
+			// requested when the renewal fails or when the lease can not be
+			// extended beyond the renew window anymore, because it reached its
+			// maximum TTL.
+			if renewAfter <= 0 && instance.Status.Lease.Renewable {
+				lease, err := vaultClient.RenewLease(ctx, instance.Status.Lease.ID, time.Duration(instance.Status.Lease.Duration)*time.Second, instance.Spec.VaultNamespace)
+				if err != nil {
+					log.Error(err, "Could not renew lease, request new credentials")
+				} else {
+					instance.Status.Lease.ExpireTime = metav1.NewTime(time.Now().Add(lease.Duration).Truncate(time.Second))
+					renewAfter = leaseRenewAfter(instance.Status.Lease, vaultClient.GetLeaseRenew())
+					if renewAfter > 0 {
+						log.Info(fmt.Sprintf("Lease was renewed and will expire on %s", instance.Status.Lease.ExpireTime.String()))
+						reconcileResult.RequeueAfter = renewAfter
+						r.updateConditions(ctx, instance, conditionReasonLeaseRenewed, "Lease was renewed", metav1.ConditionTrue)
+						return reconcileResult, nil
+					}
+					log.Info("Lease reached its maximum TTL, request new credentials")
+				}
+			}
+
+			if renewAfter > 0 {
+				log.Info("Skip updating a Secret cause the lease is still valid", "Secret.Namespace", instance.Namespace, "Secret.Name", instance.Name)
+				log.Info(fmt.Sprintf("Lease will expire on %s and will be renewed on %s", instance.Status.Lease.ExpireTime.String(), time.Now().Add(renewAfter).String()))
+				reconcileResult.RequeueAfter = renewAfter
+				r.observeReconciliation(instance, metav1.ConditionTrue)
+				r.recordEvent(instance, corev1.EventTypeNormal, eventReasonUnchanged, eventActionReconcile, eventMessageUnchanged)
+				return reconcileResult, nil
+			}
+		}
+
+		// The lease from the status is replaced by the lease of the new
+		// credentials, e.g. because it could not be renewed, reached its
+		// maximum TTL or the Secret was deleted. It is revoked together with
+		// the other superseded leases, so that the old credentials can not be
+		// used until they expire.
+		if instance.Status.Lease != nil && instance.Status.Lease.ID != "" && !slices.Contains(supersededLeaseIDs, instance.Status.Lease.ID) {
+			supersededLeaseIDs = append(supersededLeaseIDs, instance.Status.Lease.ID)
+		}
+
+		var lease *vault.Lease
+		if instance.Spec.SecretEngine == awsEngine {
+			data, lease, err = vaultClient.GetAWSCredentials(ctx, instance.Spec.Path, instance.Spec.Role, instance.Spec.EngineOptions, instance.Spec.VaultNamespace)
+		} else {
+			data, lease, err = vaultClient.GetDatabaseCredentials(ctx, instance.Spec.Path, instance.Spec.Role, instance.Spec.VaultNamespace)
+		}
+		if err != nil {
+			log.Error(err, "Could not get credentials from vault")
+			r.updateConditions(ctx, instance, conditionReasonFetchFailed, fetchFailedMessage(instance.Spec.Path, err), metav1.ConditionFalse)
+			return ctrl.Result{}, err
+		}
+
+		// Save the lease in the status of the VaultSecret, so that we know when
+		// the credentials must be renewed. The status is written together with
+		// the conditions, after the Secret was created or updated.
+		instance.Status.Lease = newLeaseStatus(lease)
+		if instance.Status.Lease != nil {
+			log.Info(fmt.Sprintf("Lease will expire on %s", instance.Status.Lease.ExpireTime.String()))
+			ra := leaseRenewAfter(instance.Status.Lease, vaultClient.GetLeaseRenew())
+			if ra <= 0 {
+				reconcileResult.RequeueAfter = 0 * time.Second
+			} else {
+				reconcileResult.RequeueAfter = ra
+				log.Info(fmt.Sprintf("Lease will be renewed on %s", time.Now().Add(ra).String()))
+			}
+		}
 	}
 
 	// Define a new Secret object
-	secret, err := newSecretForCR(instance, data, secretsPaths)
+	secret, err := newSecretForCR(ctx, instance, data, secretsPaths)
 	if err != nil {
 		// Error while creating the Kubernetes secret - requeue the request.
 		log.Error(err, "Could not create Kubernetes secret")
@@ -332,6 +780,16 @@ func (r *VaultSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
+	// Save the ID of the lease in the Secret, so that we know the lease of the
+	// credentials, even when the status could not be updated.
+	if (instance.Spec.SecretEngine == databaseEngine || instance.Spec.SecretEngine == awsEngine) && instance.Status.Lease != nil && instance.Status.Lease.ID != "" {
+		secret.Annotations = maps.Clone(secret.Annotations)
+		if secret.Annotations == nil {
+			secret.Annotations = make(map[string]string)
+		}
+		secret.Annotations[leaseIDAnnotation] = instance.Status.Lease.ID
+	}
+
 	// Set VaultSecret instance as the owner and controller
 	err = ctrl.SetControllerReference(instance, secret, r.Scheme)
 	if err != nil {
@@ -345,7 +803,7 @@ func (r *VaultSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	err = r.Get(ctx, types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace}, found)
 	if err != nil && errors.IsNotFound(err) {
 		log.Info("Creating a new Secret", "Secret.Namespace", secret.Namespace, "Secret.Name", secret.Name)
-		err = r.Create(ctx, secret)
+		err = r.createSecret(ctx, secret)
 		if err != nil {
 			log.Error(err, "Could not create secret")
 			r.updateConditions(ctx, instance, conditionReasonCreateFailed, err.Error(), metav1.ConditionFalse)
@@ -353,6 +811,10 @@ func (r *VaultSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		}
 
 		// Secret created successfully - requeue only if no version is specified
+		if certExpiration != nil {
+			r.setCertificateMetrics(instance, *certExpiration, vaultClient.GetPKIRenew())
+		}
+		r.revokeSupersededLeases(ctx, instance, vaultClient, supersededLeaseIDs)
 		r.updateConditions(ctx, instance, conditionReasonCreated, "Secret was created", metav1.ConditionTrue)
 		return reconcileResult, nil
 	} else if err != nil {
@@ -370,15 +832,21 @@ func (r *VaultSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 		if secret.Type == found.Type && reflect.DeepEqual(secret.Data, found.Data) && reflect.DeepEqual(secret.Labels, found.Labels) && reflect.DeepEqual(secret.Annotations, found.Annotations) && len(instance.Status.Conditions) == 1 && instance.Status.Conditions[0].Status == metav1.ConditionTrue {
 			log.Info("Skip updating a Secret cause data no change", "Secret.Namespace", secret.Namespace, "Secret.Name", secret.Name)
+			r.recordEvent(instance, corev1.EventTypeNormal, eventReasonUnchanged, eventActionReconcile, eventMessageUnchanged)
 		} else {
 			log.Info("Updating a Secret", "Secret.Namespace", secret.Namespace, "Secret.Name", secret.Name)
-			err = r.Update(ctx, secret)
+			err = r.updateSecret(ctx, secret)
 			if err != nil {
 				log.Error(err, "Could not update secret")
 				r.updateConditions(ctx, instance, conditionReasonMergeFailed, err.Error(), metav1.ConditionFalse)
 				return ctrl.Result{}, err
 			}
+			r.revokeSupersededLeases(ctx, instance, vaultClient, supersededLeaseIDs)
+			addPendingRevocation(instance, previousSerialNumber)
 			r.updateConditions(ctx, instance, conditionReasonUpdated, "Secret was updated", metav1.ConditionTrue)
+			if certificateRenewed {
+				r.recordEvent(instance, corev1.EventTypeNormal, eventReasonCertificateRenewed, eventActionReconcile, "Certificate was renewed with serial number %s", instance.Status.SerialNumber)
+			}
 		}
 	} else {
 		if secret.Type == found.Type && reflect.DeepEqual(secret.Data, found.Data) && reflect.DeepEqual(secret.Labels, found.Labels) && reflect.DeepEqual(secret.Annotations, found.Annotations) && len(instance.Status.Conditions) == 1 && instance.Status.Conditions[0].Status == metav1.ConditionTrue {
@@ -388,30 +856,305 @@ func (r *VaultSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			// reflect that the reconciliation was successful, even if there was
 			// no change.
 			log.Info("Skip updating a Secret cause no change", "Secret.Namespace", secret.Namespace, "Secret.Name", secret.Name)
-			vaultSecretsReconciliationsTotal.WithLabelValues(instance.Namespace, instance.Name, string(metav1.ConditionTrue)).Inc()
-			vaultSecretsReconciliationStatus.WithLabelValues(instance.Namespace, instance.Name).Set(1)
+			r.observeReconciliation(instance, metav1.ConditionTrue)
+			r.recordEvent(instance, corev1.EventTypeNormal, eventReasonUnchanged, eventActionReconcile, eventMessageUnchanged)
 		} else {
 			log.Info("Updating a Secret", "Secret.Namespace", secret.Namespace, "Secret.Name", secret.Name)
-			err = r.Update(ctx, secret)
+			err = r.updateSecret(ctx, secret)
 			if err != nil {
 				log.Error(err, "Could not update secret")
 				r.updateConditions(ctx, instance, conditionReasonUpdateFailed, err.Error(), metav1.ConditionFalse)
 				return ctrl.Result{}, err
 			}
+			r.revokeSupersededLeases(ctx, instance, vaultClient, supersededLeaseIDs)
+			addPendingRevocation(instance, previousSerialNumber)
 			r.updateConditions(ctx, instance, conditionReasonUpdated, "Secret was updated", metav1.ConditionTrue)
+			if certificateRenewed {
+				r.recordEvent(instance, corev1.EventTypeNormal, eventReasonCertificateRenewed, eventActionReconcile, "Certificate was renewed with serial number %s", instance.Status.SerialNumber)
+			}
 		}
 	}
 
 	// Secret updated successfully - requeue only if no version is specified
+	if certExpiration != nil {
+		r.setCertificateMetrics(instance, *certExpiration, vaultClient.GetPKIRenew())
+	}
 	return reconcileResult, nil
 }
 
-func (r *VaultSecretReconciler) updateConditions(ctx context.Context, instance *ricobergerdev1alpha1.VaultSecret, reason, message string, status metav1.ConditionStatus) {
-	vaultSecretsReconciliationsTotal.WithLabelValues(instance.Namespace, instance.Name, string(status)).Inc()
+// createSecret creates the given Secret.
+func (r *VaultSecretReconciler) createSecret(ctx context.Context, secret *corev1.Secret) (err error) {
+	ctx, span := tracer.Start(ctx, "CreateSecret", trace.WithAttributes(
+		attribute.String("secret.namespace", secret.Namespace),
+		attribute.String("secret.name", secret.Name),
+	))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	return r.Create(ctx, secret)
+}
+
+// updateSecret updates the given Secret.
+func (r *VaultSecretReconciler) updateSecret(ctx context.Context, secret *corev1.Secret) (err error) {
+	ctx, span := tracer.Start(ctx, "UpdateSecret", trace.WithAttributes(
+		attribute.String("secret.namespace", secret.Namespace),
+		attribute.String("secret.name", secret.Name),
+	))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	return r.Update(ctx, secret)
+}
+
+// getVaultClient returns the Vault client which should be used for the given
+// VaultSecret. If the VaultSecret contains the vaultRole property we are using
+// the client for the specified Vault Role from the client pool or creating a
+// new client, when the pool is disabled. When the property isn't set we are
+// using the shared client. It is also possible that the shared client is nil,
+// so that we have to check for this first. This could happen since we do not
+// return an error when we initializing the client during start up, to not
+// require a default Vault Role.
+func (r *VaultSecretReconciler) getVaultClient(ctx context.Context, instance *ricobergerdev1alpha1.VaultSecret) (*vault.Client, error) {
+	log := logr.FromContext(ctx)
+
+	if instance.Spec.VaultRole != "" {
+		// When the operator is configured to request tokens for the service
+		// accounts of the secrets, we authenticate with the service account
+		// from the namespace of the secret, so that the bound namespaces of
+		// the Vault role are enforced.
+		var serviceAccountToken vault.ServiceAccountToken
+		var namespace, serviceAccount string
+		if vault.ServiceAccountTokenRequest {
+			namespace, serviceAccount = instance.Namespace, serviceAccountName(instance)
+			serviceAccountToken = r.serviceAccountToken(ctx, instance)
+			log = log.WithValues("serviceAccountName", serviceAccount)
+		}
+
+		if r.ClientPool != nil {
+			log.WithValues("vaultRole", instance.Spec.VaultRole).Info("Use pooled client to get secret from Vault")
+			return r.ClientPool.Get(instance.Spec.VaultRole, namespace, serviceAccount, serviceAccountToken)
+		}
+
+		log.WithValues("vaultRole", instance.Spec.VaultRole).Info("Create client to get secret from Vault")
+		return vault.CreateClientWithServiceAccountToken(instance.Spec.VaultRole, serviceAccountToken)
+	}
+
+	log.Info("Use shared client to get secret from Vault")
+	if vault.SharedClient == nil {
+		return nil, fmt.Errorf("shared client not initialized and vaultRole property missing")
+	}
+
+	return vault.SharedClient, nil
+}
+
+// revoke revokes the lease or the certificate, which was issued by Vault for
+// the given VaultSecret. The lease ID and the serial number of the certificate
+// are taken from the status of the VaultSecret. For certificates issued before
+// the serial number was saved in the status, we fall back to the serial number
+// from the Kubernetes secret. Previous certificates which are still waiting for
+// their revocation are revoked as well. Nothing is revoked when the skipRevoke
+// property is set, e.g. because the credentials are shared with other
+// workloads. When Vault rejects a revocation with a permanent error (e.g. the
+// policy doesn't allow the revocation or the certificate doesn't exist
+// anymore), the revocation is skipped and a warning event is emitted, because
+// retrying the revocation would block the deletion forever.
+func (r *VaultSecretReconciler) revoke(ctx context.Context, instance *ricobergerdev1alpha1.VaultSecret) error {
+	log := logr.FromContext(ctx)
+
+	if instance.Spec.SkipRevoke {
+		return nil
+	}
+
+	switch instance.Spec.SecretEngine {
+	case pkiEngine:
+		serialNumber := instance.Status.SerialNumber
+		if serialNumber == "" {
+			secret := &corev1.Secret{}
+			err := r.Get(ctx, types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, secret)
+			if err != nil {
+				return client.IgnoreNotFound(err)
+			}
+			serialNumber = string(secret.Data["serial_number"])
+		}
+
+		// The previous certificates, which are still waiting for their
+		// revocation, are revoked together with the current certificate.
+		serialNumbers := make([]string, 0, len(instance.Status.PendingRevocations)+1)
+		for _, revocation := range instance.Status.PendingRevocations {
+			serialNumbers = append(serialNumbers, revocation.SerialNumber)
+		}
+		if serialNumber != "" {
+			serialNumbers = append(serialNumbers, serialNumber)
+		}
+
+		if len(serialNumbers) == 0 {
+			return nil
+		}
+
+		vaultClient, err := r.getVaultClient(ctx, instance)
+		if err != nil {
+			return err
+		}
+
+		for _, serialNumber := range serialNumbers {
+			log.Info("Revoke certificate", "serialNumber", serialNumber)
+			if err := vaultClient.RevokeCertificate(ctx, instance.Spec.Path, serialNumber, instance.Spec.VaultNamespace); err != nil {
+				if !vault.IsPermanentError(err) {
+					return err
+				}
+
+				log.Error(err, "Could not revoke certificate, skip revocation", "serialNumber", serialNumber)
+				r.recordEvent(instance, corev1.EventTypeWarning, eventReasonRevokeSkipped, eventActionRevoke, "Certificate %s was not revoked: %s", serialNumber, err.Error())
+			}
+		}
+
+		return nil
+
+	default:
+		if instance.Status.Lease == nil || instance.Status.Lease.ID == "" {
+			return nil
+		}
+
+		vaultClient, err := r.getVaultClient(ctx, instance)
+		if err != nil {
+			return err
+		}
+
+		log.Info("Revoke lease", "leaseID", instance.Status.Lease.ID)
+		if err := vaultClient.RevokeLease(ctx, instance.Status.Lease.ID, instance.Spec.VaultNamespace); err != nil {
+			if !vault.IsPermanentError(err) {
+				return err
+			}
+
+			log.Error(err, "Could not revoke lease, skip revocation", "leaseID", instance.Status.Lease.ID)
+			r.recordEvent(instance, corev1.EventTypeWarning, eventReasonRevokeSkipped, eventActionRevoke, "Lease %s was not revoked: %s", instance.Status.Lease.ID, err.Error())
+			return nil
+		}
+
+		r.recordEvent(instance, corev1.EventTypeNormal, eventReasonLeaseRevoked, eventActionRevoke, "Lease %s was revoked", instance.Status.Lease.ID)
+		return nil
+	}
+}
+
+// revokeSupersededLeases revokes the leases with the given IDs, whose
+// credentials were replaced in the Secret before the leases had to be renewed,
+// e.g. because the spec of the VaultSecret changed. A failed revocation is only
+// logged, because the credentials expire with the lease anyway. Nothing is
+// revoked when the skipRevoke property is set.
+func (r *VaultSecretReconciler) revokeSupersededLeases(ctx context.Context, instance *ricobergerdev1alpha1.VaultSecret, vaultClient *vault.Client, leaseIDs []string) {
+	if instance.Spec.SkipRevoke {
+		return
+	}
+
+	log := logr.FromContext(ctx)
+	for _, leaseID := range leaseIDs {
+		if instance.Status.Lease != nil && instance.Status.Lease.ID == leaseID {
+			continue
+		}
+
+		log.Info("Revoke superseded lease", "leaseID", leaseID)
+		if err := vaultClient.RevokeLease(ctx, leaseID, instance.Spec.VaultNamespace); err != nil {
+			log.Error(err, "Could not revoke superseded lease", "leaseID", leaseID)
+			continue
+		}
+
+		r.recordEvent(instance, corev1.EventTypeNormal, eventReasonLeaseRevoked, eventActionRevoke, "Superseded lease %s was revoked", leaseID)
+	}
+}
+
+// metricsLabels returns the values of the namespace and name labels for the
+// metrics of the given VaultSecret. The values are empty, when the labels are
+// omitted.
+func (r *VaultSecretReconciler) metricsLabels(instance *ricobergerdev1alpha1.VaultSecret) (string, string) {
+	if r.OmitMetricsObjectLabels {
+		return "", ""
+	}
+	return instance.Namespace, instance.Name
+}
+
+// observeReconciliation records the status of a reconciliation of the given
+// VaultSecret.
+func (r *VaultSecretReconciler) observeReconciliation(instance *ricobergerdev1alpha1.VaultSecret, status metav1.ConditionStatus) {
+	namespace, name := r.metricsLabels(instance)
+	vaultSecretsReconciliationsTotal.WithLabelValues(namespace, name, string(status)).Inc()
+
+	if r.OmitMetricsObjectLabels {
+		return
+	}
 	if status == metav1.ConditionTrue {
-		vaultSecretsReconciliationStatus.WithLabelValues(instance.Namespace, instance.Name).Set(1)
+		vaultSecretsReconciliationStatus.WithLabelValues(namespace, name).Set(1)
 	} else {
-		vaultSecretsReconciliationStatus.WithLabelValues(instance.Namespace, instance.Name).Set(0)
+		vaultSecretsReconciliationStatus.WithLabelValues(namespace, name).Set(0)
+	}
+}
+
+// observeReconciliationDuration records the duration of a reconciliation of
+// the given VaultSecret, which was started at the given time.
+func (r *VaultSecretReconciler) observeReconciliationDuration(instance *ricobergerdev1alpha1.VaultSecret, start time.Time) {
+	namespace, name := r.metricsLabels(instance)
+	vaultSecretsReconciliationDurationSeconds.WithLabelValues(namespace, name).Observe(time.Since(start).Seconds())
+}
+
+// setCertificateMetrics sets the expiration date of the certificate of the
+// given VaultSecret and the time when the certificate is renewed, which is the
+// given renew period before the expiration date.
+func (r *VaultSecretReconciler) setCertificateMetrics(instance *ricobergerdev1alpha1.VaultSecret, expiration time.Time, pkiRenew time.Duration) {
+	if r.OmitMetricsObjectLabels {
+		return
+	}
+	vaultSecretsCertificateExpirationTimestampSeconds.WithLabelValues(instance.Namespace, instance.Name).Set(float64(expiration.Unix()))
+	vaultSecretsCertificateRenewalTimestampSeconds.WithLabelValues(instance.Namespace, instance.Name).Set(float64(expiration.Add(-pkiRenew).Unix()))
+}
+
+// deleteMetrics removes the metrics of the given VaultSecret, when it is
+// deleted. When the namespace and name labels are omitted, the metrics are
+// shared by all VaultSecrets and are kept.
+func (r *VaultSecretReconciler) deleteMetrics(instance *ricobergerdev1alpha1.VaultSecret) {
+	if r.OmitMetricsObjectLabels {
+		return
+	}
+
+	labels := prometheus.Labels{"namespace": instance.Namespace, "name": instance.Name}
+	vaultSecretsReconciliationsTotal.DeletePartialMatch(labels)
+	vaultSecretsReconciliationStatus.DeletePartialMatch(labels)
+	vaultSecretsReconciliationFailuresTotal.DeletePartialMatch(labels)
+	vaultSecretsReconciliationDurationSeconds.DeletePartialMatch(labels)
+	vaultSecretsCertificateExpirationTimestampSeconds.DeletePartialMatch(labels)
+	vaultSecretsCertificateRenewalTimestampSeconds.DeletePartialMatch(labels)
+}
+
+// recordEvent emits a Kubernetes Event for the given VaultSecret, if an event
+// recorder is configured.
+func (r *VaultSecretReconciler) recordEvent(instance *ricobergerdev1alpha1.VaultSecret, eventtype, reason, action, note string, args ...any) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(instance, nil, eventtype, reason, action, note, args...)
+}
+
+// fetchFailedMessage returns the message for the condition and the event, when
+// the secret for the given path could not be fetched from Vault.
+func fetchFailedMessage(path string, err error) string {
+	return fmt.Sprintf("Could not fetch %s from Vault: %s", path, err.Error())
+}
+
+// updateConditions sets the condition of the given VaultSecret and emits an
+// event with the same reason and message, so that the result of the
+// reconciliation is also shown by "kubectl describe". A failure is only
+// emitted as event, when the condition changed, so that a VaultSecret, which
+// fails with the same error and is retried with a backoff, does not flood the
+// events API. A failure with another message, e.g. for another path, is
+// emitted. For a successful reconciliation the generation of the
+// VaultSecret is saved as observed generation.
+func (r *VaultSecretReconciler) updateConditions(ctx context.Context, instance *ricobergerdev1alpha1.VaultSecret, reason, message string, status metav1.ConditionStatus) {
+	r.observeReconciliation(instance, status)
+	if status == metav1.ConditionFalse {
+		namespace, name := r.metricsLabels(instance)
+		vaultSecretsReconciliationFailuresTotal.WithLabelValues(namespace, name, reason).Inc()
+		if len(instance.Status.Conditions) != 1 || instance.Status.Conditions[0].Reason != reason || instance.Status.Conditions[0].Status != status || instance.Status.Conditions[0].Message != message {
+			r.recordEvent(instance, corev1.EventTypeWarning, reason, eventActionReconcile, "%s", message)
+		}
+	} else {
+		instance.Status.ObservedGeneration = instance.GetGeneration()
+		r.recordEvent(instance, corev1.EventTypeNormal, reason, eventActionReconcile, "%s", message)
 	}
 
 	instance.Status.Conditions = []metav1.Condition{{
@@ -485,6 +1228,7 @@ func (r *VaultSecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			For(&ricobergerdev1alpha1.VaultSecret{}).
 			Owns(&corev1.Secret{}).
 			WithEventFilter(ignorePredicate()).
+			WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
 			Complete(r)
 	}
 
@@ -499,6 +1243,7 @@ func (r *VaultSecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Watches(&corev1.Namespace{},
 			handler.EnqueueRequestsFromMapFunc(r.mapNamespaceToVaultSecrets),
 			builder.WithPredicates(r.namespaceBecameMatching())).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
 		Complete(r)
 }
 
@@ -550,9 +1295,12 @@ type templateContext struct {
 	Annotations  map[string]string
 }
 
-// runTemplate executes a template with the given secrets map, filled with the
-// Vault secrets
-func runTemplate(cr *ricobergerdev1alpha1.VaultSecret, tmpl string, secrets map[string][]byte, secretsPaths []secretPath) ([]byte, error) {
+// runTemplate executes the template for the given key with the given secrets
+// map, filled with the Vault secrets.
+func runTemplate(ctx context.Context, cr *ricobergerdev1alpha1.VaultSecret, key, tmpl string, secrets map[string][]byte, secretsPaths []secretPath) (_ []byte, err error) {
+	_, span := tracer.Start(ctx, "runTemplate", trace.WithAttributes(attribute.String("template.key", key)))
+	defer func() { tracing.EndSpan(span, err) }()
+
 	// Set up the context
 	sd := templateContext{
 		Secrets: make(map[string]string, len(secrets)),
@@ -632,11 +1380,11 @@ func templatingFunctions() template.FuncMap {
 
 // newSecretForCR returns a secret with the same name/namespace as the CR. The
 // secret will include all labels and annotations from the CR.
-func newSecretForCR(cr *ricobergerdev1alpha1.VaultSecret, data map[string][]byte, secretsPaths []secretPath) (*corev1.Secret, error) {
+func newSecretForCR(ctx context.Context, cr *ricobergerdev1alpha1.VaultSecret, data map[string][]byte, secretsPaths []secretPath) (*corev1.Secret, error) {
 	if cr.Spec.Templates != nil {
 		newdata := make(map[string][]byte)
 		for k, v := range cr.Spec.Templates {
-			templated, err := runTemplate(cr, v, data, secretsPaths)
+			templated, err := runTemplate(ctx, cr, k, v, data, secretsPaths)
 			if err != nil {
 				return nil, fmt.Errorf("template ERROR: %w", err)
 			}
@@ -706,3 +1454,32 @@ func certificateExpiration(data map[string][]byte) (time.Time, bool) {
 
 	return expiration, found
 }
+
+// newLeaseStatus converts the lease returned by Vault into the lease which is
+// saved in the status of a VaultSecret. The expiration time of the lease is
+// calculated based on the current time.
+func newLeaseStatus(lease *vault.Lease) *ricobergerdev1alpha1.VaultSecretLease {
+	if lease == nil {
+		return nil
+	}
+
+	return &ricobergerdev1alpha1.VaultSecretLease{
+		ID:         lease.ID,
+		Duration:   int(lease.Duration.Seconds()),
+		Renewable:  lease.Renewable,
+		ExpireTime: metav1.NewTime(time.Now().Add(lease.Duration).Truncate(time.Second)),
+	}
+}
+
+// leaseRenewAfter returns the duration after which the given lease must be
+// renewed, which is the remaining lifetime of the lease minus the renew window.
+// For short lived leases the renew window is limited to a third of the lease
+// duration, so that the credentials are not renewed on every reconcile when the
+// lease duration is shorter than the configured renew window.
+func leaseRenewAfter(lease *ricobergerdev1alpha1.VaultSecretLease, renew time.Duration) time.Duration {
+	if maxRenew := time.Duration(lease.Duration) * time.Second / 3; renew > maxRenew {
+		renew = maxRenew
+	}
+
+	return time.Until(lease.ExpireTime.Time) - renew
+}
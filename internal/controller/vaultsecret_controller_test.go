@@ -1,19 +1,40 @@
 package controller
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/json"
 	"encoding/pem"
+	"errors"
+	"fmt"
 	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	ricobergerdev1alpha1 "github.com/ricoberger/vault-secrets-operator/api/v1alpha1"
+	"github.com/ricoberger/vault-secrets-operator/internal/tracing/tracingtest"
+	"github.com/ricoberger/vault-secrets-operator/internal/vault"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 // testCertPEM generates a self-signed certificate which expires at notAfter and
@@ -176,7 +197,7 @@ func TestNewSecretForCRMultiplePaths(t *testing.T) {
 	data := mergeSecretsPaths(secretsPaths)
 
 	t.Run("without templates uses first-wins merged data", func(t *testing.T) {
-		secret, err := newSecretForCR(cr, data, secretsPaths)
+		secret, err := newSecretForCR(context.Background(), cr, data, secretsPaths)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -192,7 +213,7 @@ func TestNewSecretForCRMultiplePaths(t *testing.T) {
 			"firstWin": "{% .Secrets.shared %}",
 		}
 
-		secret, err := newSecretForCR(crWithTemplates, data, secretsPaths)
+		secret, err := newSecretForCR(context.Background(), crWithTemplates, data, secretsPaths)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -204,3 +225,525 @@ func TestNewSecretForCRMultiplePaths(t *testing.T) {
 		}
 	})
 }
+
+// TestReconcileSpans verifies that the reconciliation and the rendering of
+// every template are recorded as spans, where the spans for the templates are
+// children of the span from the given context and the spans for the requests
+// against Vault and the Kubernetes API are children of the Reconcile span.
+func TestReconcileSpans(t *testing.T) {
+	exporter := tracingtest.NewExporter(t)
+
+	scheme := runtime.NewScheme()
+	if err := ricobergerdev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add scheme: %v", err)
+	}
+
+	r := &VaultSecretReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).Build()}
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "missing"}}); err != nil {
+		t.Fatalf("Reconcile returned an error: %v", err)
+	}
+
+	ctx, span := tracer.Start(context.Background(), "parent")
+	cr := &ricobergerdev1alpha1.VaultSecret{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: ricobergerdev1alpha1.VaultSecretSpec{
+			Templates: map[string]string{"username": "{% .Secrets.username %}"},
+		},
+	}
+	if _, err := newSecretForCR(ctx, cr, map[string][]byte{"username": []byte("admin")}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	span.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("spans = %d, want 3", len(spans))
+	}
+	if spans[0].Name != "Reconcile" {
+		t.Errorf("span = %s, want Reconcile", spans[0].Name)
+	}
+	if spans[1].Name != "runTemplate" || spans[1].Parent.SpanID() != spans[2].SpanContext.SpanID() {
+		t.Errorf("span = %s with parent %s, want runTemplate with parent %s", spans[1].Name, spans[1].Parent.SpanID(), spans[2].SpanContext.SpanID())
+	}
+
+	// The spans for the requests against Vault and the Kubernetes API are
+	// children of the Reconcile span, when the Secret is created.
+	exporter.Reset()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasPrefix(r.URL.Path, "/v1/sys/internal/ui/mounts/") {
+			_, _ = w.Write([]byte(`{"data": {"path": "kv/", "options": null}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"username": "admin"}}`))
+	}))
+	defer srv.Close()
+	newTestVaultClient(t, srv.URL)
+
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add scheme: %v", err)
+	}
+	instance := &ricobergerdev1alpha1.VaultSecret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kv", Finalizers: []string{vaultsecretsFinalizer}},
+		Spec:       ricobergerdev1alpha1.VaultSecretSpec{Path: "kv/app", Type: corev1.SecretTypeOpaque},
+	}
+	r = &VaultSecretReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(instance).WithStatusSubresource(instance).Build(), Scheme: scheme}
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "kv"}}); err != nil {
+		t.Fatalf("Reconcile returned an error: %v", err)
+	}
+
+	spans = exporter.GetSpans()
+	reconcile := slices.IndexFunc(spans, func(s tracetest.SpanStub) bool { return s.Name == "Reconcile" })
+	if reconcile < 0 {
+		t.Fatal("expected a Reconcile span")
+	}
+	for _, name := range []string{"GetSecret", "CreateSecret"} {
+		i := slices.IndexFunc(spans, func(s tracetest.SpanStub) bool { return s.Name == name })
+		if i < 0 {
+			t.Errorf("expected a %s span", name)
+			continue
+		}
+		if spans[i].Parent.SpanID() != spans[reconcile].SpanContext.SpanID() {
+			t.Errorf("%s span has parent %s, want %s", name, spans[i].Parent.SpanID(), spans[reconcile].SpanContext.SpanID())
+		}
+	}
+}
+
+// TestSetCertificateMetrics verifies that the expiration date and the renewal
+// time of a certificate are exported per VaultSecret.
+func TestSetCertificateMetrics(t *testing.T) {
+	instance := &ricobergerdev1alpha1.VaultSecret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "certificate"},
+	}
+	expiration := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	r := &VaultSecretReconciler{}
+	r.setCertificateMetrics(instance, expiration, 24*time.Hour)
+
+	if got := testutil.ToFloat64(vaultSecretsCertificateExpirationTimestampSeconds.WithLabelValues("default", "certificate")); got != float64(expiration.Unix()) {
+		t.Errorf("expiration = %f, want %d", got, expiration.Unix())
+	}
+	if got, want := testutil.ToFloat64(vaultSecretsCertificateRenewalTimestampSeconds.WithLabelValues("default", "certificate")), float64(expiration.Add(-24*time.Hour).Unix()); got != want {
+		t.Errorf("renewal = %f, want %f", got, want)
+	}
+}
+
+// TestReconciliationMetrics verifies that failed reconciliations are counted by
+// reason and that the namespace and name labels can be omitted.
+func TestReconciliationMetrics(t *testing.T) {
+	instance := &ricobergerdev1alpha1.VaultSecret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "metrics"},
+	}
+
+	t.Run("labels are set", func(t *testing.T) {
+		r := &VaultSecretReconciler{Client: fake.NewClientBuilder().Build()}
+		r.updateConditions(context.Background(), instance, conditionReasonFetchFailed, "could not read secret", metav1.ConditionFalse)
+		r.observeReconciliationDuration(instance, time.Now())
+
+		if got := testutil.ToFloat64(vaultSecretsReconciliationStatus.WithLabelValues("default", "metrics")); got != 0 {
+			t.Errorf("status = %f, want 0", got)
+		}
+		if got := testutil.ToFloat64(vaultSecretsReconciliationFailuresTotal.WithLabelValues("default", "metrics", conditionReasonFetchFailed)); got != 1 {
+			t.Errorf("failures = %f, want 1", got)
+		}
+		if got := testutil.CollectAndCount(vaultSecretsReconciliationDurationSeconds); got == 0 {
+			t.Error("expected the reconciliation duration to be recorded")
+		}
+
+		r.deleteMetrics(instance)
+		if got := testutil.ToFloat64(vaultSecretsReconciliationFailuresTotal.WithLabelValues("default", "metrics", conditionReasonFetchFailed)); got != 0 {
+			t.Errorf("failures = %f, want 0 after the metrics were deleted", got)
+		}
+	})
+
+	t.Run("labels are omitted", func(t *testing.T) {
+		r := &VaultSecretReconciler{OmitMetricsObjectLabels: true}
+		if namespace, name := r.metricsLabels(instance); namespace != "" || name != "" {
+			t.Errorf("labels = %q, %q, want empty labels", namespace, name)
+		}
+
+		total := testutil.ToFloat64(vaultSecretsReconciliationsTotal.WithLabelValues("", "", string(metav1.ConditionTrue)))
+		r.observeReconciliation(instance, metav1.ConditionTrue)
+		if got := testutil.ToFloat64(vaultSecretsReconciliationsTotal.WithLabelValues("", "", string(metav1.ConditionTrue))); got != total+1 {
+			t.Errorf("reconciliations = %f, want %f", got, total+1)
+		}
+
+		vaultSecretsReconciliationStatus.Reset()
+		r.observeReconciliation(instance, metav1.ConditionTrue)
+		if got := testutil.CollectAndCount(vaultSecretsReconciliationStatus); got != 0 {
+			t.Errorf("status series = %d, want 0", got)
+		}
+	})
+}
+
+// TestLeaseRenewAfter verifies that the renew window is applied to the lease
+// expiration and limited to a third of the lease duration for short lived
+// leases.
+func TestLeaseRenewAfter(t *testing.T) {
+	now := time.Now()
+
+	t.Run("renew window is applied", func(t *testing.T) {
+		lease := &ricobergerdev1alpha1.VaultSecretLease{
+			Duration:   int((24 * time.Hour).Seconds()),
+			ExpireTime: metav1.NewTime(now.Add(24 * time.Hour)),
+		}
+
+		got := leaseRenewAfter(lease, time.Hour)
+		if want := 23 * time.Hour; got > want || got < want-time.Minute {
+			t.Errorf("renewAfter = %s, want about %s", got, want)
+		}
+	})
+
+	t.Run("renew window is limited for short lived leases", func(t *testing.T) {
+		lease := &ricobergerdev1alpha1.VaultSecretLease{
+			Duration:   int((3 * time.Minute).Seconds()),
+			ExpireTime: metav1.NewTime(now.Add(3 * time.Minute)),
+		}
+
+		got := leaseRenewAfter(lease, time.Hour)
+		if want := 2 * time.Minute; got > want || got < want-time.Minute {
+			t.Errorf("renewAfter = %s, want about %s", got, want)
+		}
+	})
+
+	t.Run("expired lease", func(t *testing.T) {
+		lease := &ricobergerdev1alpha1.VaultSecretLease{
+			Duration:   int(time.Hour.Seconds()),
+			ExpireTime: metav1.NewTime(now.Add(-time.Minute)),
+		}
+
+		if got := leaseRenewAfter(lease, 5*time.Minute); got > 0 {
+			t.Errorf("renewAfter = %s, want a value <= 0", got)
+		}
+	})
+}
+
+// TestRecordEvents verifies that the conditions are emitted as events, that a
+// repeated failure is not emitted again, while a failure for another path is
+// emitted, and that no events are emitted without a recorder.
+func TestRecordEvents(t *testing.T) {
+	instance := &ricobergerdev1alpha1.VaultSecret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "events"},
+	}
+
+	recorder := events.NewFakeRecorder(10)
+	r := &VaultSecretReconciler{Client: fake.NewClientBuilder().Build(), Recorder: recorder}
+	r.updateConditions(context.Background(), instance, conditionReasonCreated, "Secret was created", metav1.ConditionTrue)
+	r.updateConditions(context.Background(), instance, conditionReasonFetchFailed, fetchFailedMessage("kvv2/app", errors.New("permission denied")), metav1.ConditionFalse)
+	r.updateConditions(context.Background(), instance, conditionReasonFetchFailed, fetchFailedMessage("kvv2/app", errors.New("permission denied")), metav1.ConditionFalse)
+	r.updateConditions(context.Background(), instance, conditionReasonFetchFailed, fetchFailedMessage("kvv2/other", errors.New("permission denied")), metav1.ConditionFalse)
+	r.recordEvent(instance, corev1.EventTypeNormal, eventReasonUnchanged, eventActionReconcile, eventMessageUnchanged)
+	r.recordEvent(instance, corev1.EventTypeNormal, eventReasonLeaseRevoked, eventActionRevoke, "Lease %s was revoked", "database/creds/app/1234")
+
+	for _, want := range []string{
+		"Normal Created Secret was created",
+		"Warning FetchFailed Could not fetch kvv2/app from Vault: permission denied",
+		"Warning FetchFailed Could not fetch kvv2/other from Vault: permission denied",
+		"Normal Unchanged Secret was not changed",
+		"Normal LeaseRevoked Lease database/creds/app/1234 was revoked",
+	} {
+		if got := <-recorder.Events; got != want {
+			t.Errorf("event = %q, want %q", got, want)
+		}
+	}
+	if len(recorder.Events) != 0 {
+		t.Errorf("events = %d, want 0, because the repeated failure must not be emitted again", len(recorder.Events))
+	}
+
+	r.Recorder = nil
+	r.recordEvent(instance, corev1.EventTypeNormal, eventReasonLeaseRevoked, eventActionRevoke, "Lease %s was revoked", "database/creds/app/1234")
+	if len(recorder.Events) != 0 {
+		t.Errorf("events = %d, want 0", len(recorder.Events))
+	}
+}
+
+// newTestVaultClient sets the shared Vault client to a client for the given
+// Vault server, which uses the token auth method.
+func newTestVaultClient(t *testing.T, url string) {
+	t.Helper()
+
+	t.Setenv("VAULT_ADDRESS", url)
+	t.Setenv("VAULT_AUTH_METHOD", "token")
+	t.Setenv("VAULT_TOKEN", "test")
+	t.Setenv("VAULT_TOKEN_LEASE_DURATION", "3600")
+	t.Setenv("VAULT_MAX_RETRIES", "0")
+
+	vaultClient, err := vault.CreateClient("")
+	if err != nil {
+		t.Fatalf("failed to create Vault client: %v", err)
+	}
+
+	sharedClient := vault.SharedClient
+	vault.SharedClient = vaultClient
+	t.Cleanup(func() { vault.SharedClient = sharedClient })
+}
+
+// TestRevoke verifies that leases and certificates are revoked and that
+// revocations which are permanently rejected by Vault are skipped.
+func TestRevoke(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		spec    ricobergerdev1alpha1.VaultSecretSpec
+		status  ricobergerdev1alpha1.VaultSecretStatus
+		code    int
+		wantErr bool
+		event   string
+	}{
+		{
+			name:   "lease revoked",
+			spec:   ricobergerdev1alpha1.VaultSecretSpec{SecretEngine: databaseEngine},
+			status: ricobergerdev1alpha1.VaultSecretStatus{Lease: &ricobergerdev1alpha1.VaultSecretLease{ID: "database/creds/app/1234"}},
+			code:   http.StatusNoContent,
+			event:  "Normal LeaseRevoked Lease database/creds/app/1234 was revoked",
+		},
+		{
+			name:   "invalid lease",
+			spec:   ricobergerdev1alpha1.VaultSecretSpec{SecretEngine: databaseEngine},
+			status: ricobergerdev1alpha1.VaultSecretStatus{Lease: &ricobergerdev1alpha1.VaultSecretLease{ID: "database/creds/app/1234"}},
+			code:   http.StatusBadRequest,
+			event:  "Warning RevokeSkipped Lease database/creds/app/1234 was not revoked",
+		},
+		{
+			name:    "lease revocation denied",
+			spec:    ricobergerdev1alpha1.VaultSecretSpec{SecretEngine: databaseEngine},
+			status:  ricobergerdev1alpha1.VaultSecretStatus{Lease: &ricobergerdev1alpha1.VaultSecretLease{ID: "database/creds/app/1234"}},
+			code:    http.StatusForbidden,
+			wantErr: true,
+		},
+		{
+			name:    "lease revocation failed",
+			spec:    ricobergerdev1alpha1.VaultSecretSpec{SecretEngine: databaseEngine},
+			status:  ricobergerdev1alpha1.VaultSecretStatus{Lease: &ricobergerdev1alpha1.VaultSecretLease{ID: "database/creds/app/1234"}},
+			code:    http.StatusInternalServerError,
+			wantErr: true,
+		},
+		{
+			name:   "unknown certificate",
+			spec:   ricobergerdev1alpha1.VaultSecretSpec{SecretEngine: pkiEngine, Path: "pki"},
+			status: ricobergerdev1alpha1.VaultSecretStatus{SerialNumber: "01:02"},
+			code:   http.StatusBadRequest,
+			event:  "Warning RevokeSkipped Certificate 01:02 was not revoked",
+		},
+		{
+			name:   "skip revoke",
+			spec:   ricobergerdev1alpha1.VaultSecretSpec{SecretEngine: databaseEngine, SkipRevoke: true},
+			status: ricobergerdev1alpha1.VaultSecretStatus{Lease: &ricobergerdev1alpha1.VaultSecretLease{ID: "database/creds/app/1234"}},
+			code:   http.StatusInternalServerError,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.code)
+				if tc.code >= http.StatusBadRequest {
+					_, _ = w.Write([]byte(`{"errors": ["revocation failed"]}`))
+				}
+			}))
+			defer srv.Close()
+			newTestVaultClient(t, srv.URL)
+
+			instance := &ricobergerdev1alpha1.VaultSecret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "revoke"},
+				Spec:       tc.spec,
+				Status:     tc.status,
+			}
+
+			recorder := events.NewFakeRecorder(10)
+			r := &VaultSecretReconciler{Client: fake.NewClientBuilder().Build(), Recorder: recorder}
+			if err := r.revoke(context.Background(), instance); (err != nil) != tc.wantErr {
+				t.Fatalf("revoke returned error %v, want error %t", err, tc.wantErr)
+			}
+
+			if tc.event == "" {
+				if len(recorder.Events) != 0 {
+					t.Errorf("events = %d, want 0", len(recorder.Events))
+				}
+				return
+			}
+			if got := <-recorder.Events; !strings.HasPrefix(got, tc.event) {
+				t.Errorf("event = %q, want prefix %q", got, tc.event)
+			}
+		})
+	}
+}
+
+// TestReconcileFinalizer verifies that the finalizer is kept, when the
+// revocation fails, and that it is removed, when the revoke timeout is reached.
+func TestReconcileFinalizer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"errors": ["Vault is sealed"]}`, http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	newTestVaultClient(t, srv.URL)
+
+	scheme := runtime.NewScheme()
+	if err := ricobergerdev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add scheme: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name          string
+		deleted       time.Time
+		wantErr       bool
+		wantFinalizer bool
+	}{
+		{name: "revocation is retried", deleted: time.Now(), wantErr: true, wantFinalizer: true},
+		{name: "revoke timeout reached", deleted: time.Now().Add(-revokeTimeout - time.Minute)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			instance := &ricobergerdev1alpha1.VaultSecret{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:         "default",
+					Name:              "finalizer",
+					Finalizers:        []string{vaultsecretsFinalizer},
+					DeletionTimestamp: &metav1.Time{Time: tc.deleted},
+				},
+				Spec:   ricobergerdev1alpha1.VaultSecretSpec{SecretEngine: databaseEngine},
+				Status: ricobergerdev1alpha1.VaultSecretStatus{Lease: &ricobergerdev1alpha1.VaultSecretLease{ID: "database/creds/app/1234"}},
+			}
+
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(instance).WithStatusSubresource(instance).Build()
+			r := &VaultSecretReconciler{Client: c, Recorder: events.NewFakeRecorder(10)}
+
+			_, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "finalizer"}})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Reconcile returned error %v, want error %t", err, tc.wantErr)
+			}
+
+			got := &ricobergerdev1alpha1.VaultSecret{}
+			err = c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "finalizer"}, got)
+			if tc.wantFinalizer {
+				if err != nil || !controllerutil.ContainsFinalizer(got, vaultsecretsFinalizer) {
+					t.Errorf("expected the finalizer to be kept, got error %v", err)
+				}
+			} else if !apierrors.IsNotFound(err) {
+				t.Errorf("expected the VaultSecret to be deleted, got error %v", err)
+			}
+		})
+	}
+}
+
+// TestReconcileLease verifies that the lease of the existing Secret is only
+// kept, when the spec didn't change, the Secret contains the credentials of the
+// lease from the status and the lease is still valid, and that superseded
+// leases are revoked.
+func TestReconcileLease(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		generation    int64
+		secretLeaseID string
+		expiring      bool
+		renewCode     int
+		renewDuration int
+		noSecret      bool
+		wantLeaseID   string
+		wantRevoked   []string
+		wantEvent     string
+	}{
+		{name: "lease is kept", generation: 1, secretLeaseID: "database/creds/app/1", wantLeaseID: "database/creds/app/1", wantEvent: "Normal Unchanged Secret was not changed"},
+		{name: "lease without annotation is kept", generation: 1, wantLeaseID: "database/creds/app/1", wantEvent: "Normal Unchanged Secret was not changed"},
+		{name: "spec changed", generation: 2, secretLeaseID: "database/creds/app/1", wantLeaseID: "database/creds/app/new", wantRevoked: []string{"database/creds/app/1"}},
+		{name: "status is outdated", generation: 1, secretLeaseID: "database/creds/app/2", wantLeaseID: "database/creds/app/new", wantRevoked: []string{"database/creds/app/2", "database/creds/app/1"}},
+		{name: "lease renewed", generation: 1, expiring: true, renewCode: http.StatusOK, renewDuration: 3600, wantLeaseID: "database/creds/app/1"},
+		{name: "lease renewal failed", generation: 1, expiring: true, renewCode: http.StatusInternalServerError, wantLeaseID: "database/creds/app/new", wantRevoked: []string{"database/creds/app/1"}},
+		{name: "lease reached max TTL", generation: 1, expiring: true, renewCode: http.StatusOK, renewDuration: 60, wantLeaseID: "database/creds/app/new", wantRevoked: []string{"database/creds/app/1"}},
+		{name: "secret deleted", generation: 1, noSecret: true, wantLeaseID: "database/creds/app/new", wantRevoked: []string{"database/creds/app/1"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var revoked []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/v1/database/creds/app":
+					_, _ = w.Write([]byte(`{"lease_id": "database/creds/app/new", "lease_duration": 3600, "renewable": true, "data": {"username": "new", "password": "secret"}}`))
+				case "/v1/sys/leases/renew":
+					w.WriteHeader(tc.renewCode)
+					_, _ = fmt.Fprintf(w, `{"lease_id": "database/creds/app/1", "lease_duration": %d, "renewable": true}`, tc.renewDuration)
+				case "/v1/sys/leases/revoke":
+					var body struct {
+						LeaseID string `json:"lease_id"`
+					}
+					_ = json.NewDecoder(r.Body).Decode(&body)
+					revoked = append(revoked, body.LeaseID)
+					w.WriteHeader(http.StatusNoContent)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer srv.Close()
+			newTestVaultClient(t, srv.URL)
+
+			scheme := runtime.NewScheme()
+			if err := clientgoscheme.AddToScheme(scheme); err != nil {
+				t.Fatalf("failed to add scheme: %v", err)
+			}
+			if err := ricobergerdev1alpha1.AddToScheme(scheme); err != nil {
+				t.Fatalf("failed to add scheme: %v", err)
+			}
+
+			expireTime := time.Now().Add(time.Hour)
+			if tc.expiring {
+				expireTime = time.Now().Add(time.Minute)
+			}
+			instance := &ricobergerdev1alpha1.VaultSecret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "lease", Generation: tc.generation, Finalizers: []string{vaultsecretsFinalizer}},
+				Spec:       ricobergerdev1alpha1.VaultSecretSpec{SecretEngine: databaseEngine, Path: "database", Role: "app", Type: corev1.SecretTypeOpaque},
+				Status: ricobergerdev1alpha1.VaultSecretStatus{
+					ObservedGeneration: 1,
+					Lease: &ricobergerdev1alpha1.VaultSecretLease{
+						ID:         "database/creds/app/1",
+						Duration:   3600,
+						Renewable:  true,
+						ExpireTime: metav1.NewTime(expireTime),
+					},
+				},
+			}
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "lease"},
+				Data:       map[string][]byte{"username": []byte("old")},
+			}
+			if tc.secretLeaseID != "" {
+				secret.Annotations = map[string]string{leaseIDAnnotation: tc.secretLeaseID}
+			}
+
+			builder := fake.NewClientBuilder().WithScheme(scheme).WithObjects(instance).WithStatusSubresource(instance)
+			if !tc.noSecret {
+				builder = builder.WithObjects(secret)
+			}
+			c := builder.Build()
+			recorder := events.NewFakeRecorder(10)
+			r := &VaultSecretReconciler{Client: c, Scheme: scheme, Recorder: recorder}
+			if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "lease"}}); err != nil {
+				t.Fatalf("Reconcile returned an error: %v", err)
+			}
+
+			got := &ricobergerdev1alpha1.VaultSecret{}
+			if err := c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "lease"}, got); err != nil {
+				t.Fatalf("failed to get VaultSecret: %v", err)
+			}
+			if got.Status.Lease == nil || got.Status.Lease.ID != tc.wantLeaseID {
+				t.Errorf("lease = %v, want %s", got.Status.Lease, tc.wantLeaseID)
+			}
+			if !slices.Equal(revoked, tc.wantRevoked) {
+				t.Errorf("revoked = %v, want %v", revoked, tc.wantRevoked)
+			}
+			if tc.wantEvent != "" {
+				if got := <-recorder.Events; got != tc.wantEvent {
+					t.Errorf("event = %q, want %q", got, tc.wantEvent)
+				}
+			}
+
+			if tc.wantLeaseID == "database/creds/app/new" {
+				if got.Status.ObservedGeneration != tc.generation {
+					t.Errorf("observed generation = %d, want %d", got.Status.ObservedGeneration, tc.generation)
+				}
+				gotSecret := &corev1.Secret{}
+				if err := c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "lease"}, gotSecret); err != nil {
+					t.Fatalf("failed to get Secret: %v", err)
+				}
+				if gotSecret.Annotations[leaseIDAnnotation] != tc.wantLeaseID {
+					t.Errorf("lease annotation = %q, want %s", gotSecret.Annotations[leaseIDAnnotation], tc.wantLeaseID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ricoberger/vault-secrets-operator/internal/tracing/tracingtest"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// TestSetupWithoutEndpoint verifies that the global no-op tracer provider is
+// kept, when no endpoint is configured.
+func TestSetupWithoutEndpoint(t *testing.T) {
+	shutdown, err := Setup(context.Background(), "", false)
+	if err != nil {
+		t.Fatalf("Setup returned an error: %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown returned an error: %v", err)
+	}
+
+	if _, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider); ok {
+		t.Error("expected the no-op tracer provider")
+	}
+}
+
+// TestEndSpan verifies that the error is recorded in the span.
+func TestEndSpan(t *testing.T) {
+	exporter := tracingtest.NewExporter(t)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "success")
+	EndSpan(span, nil)
+	_, span = otel.Tracer("test").Start(context.Background(), "failure")
+	EndSpan(span, errors.New("permission denied"))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("spans = %d, want 2", len(spans))
+	}
+	if spans[0].Status.Code != codes.Unset {
+		t.Errorf("status = %s, want Unset", spans[0].Status.Code)
+	}
+	if spans[1].Status.Code != codes.Error || spans[1].Status.Description != "permission denied" {
+		t.Errorf("status = %s (%s), want Error (permission denied)", spans[1].Status.Code, spans[1].Status.Description)
+	}
+}
@@ -2,6 +2,7 @@ package validators
 
 import (
 	"fmt"
+	"strconv"
 
 	ricobergerdev1alpha1 "github.com/ricoberger/vault-secrets-operator/api/v1alpha1"
 )
@@ -23,6 +24,143 @@ func ValidatePKI(instance *ricobergerdev1alpha1.VaultSecret) error {
 		return fmt.Errorf("'engineOptions.common_name' must be set")
 	}
 
+	if mode, ok := instance.Spec.EngineOptions["mode"]; ok && mode != "issue" && mode != "sign" {
+		return fmt.Errorf("'engineOptions.mode' must be 'issue' or 'sign'")
+	}
+
+	keyType := instance.Spec.EngineOptions["private_key_type"]
+	switch keyType {
+	case "", "rsa", "ec", "ed25519":
+	default:
+		return fmt.Errorf("'engineOptions.private_key_type' must be 'rsa', 'ec' or 'ed25519'")
+	}
+
+	if bits, ok := instance.Spec.EngineOptions["private_key_bits"]; ok {
+		switch {
+		case keyType == "" || keyType == "rsa":
+			if bits != "2048" && bits != "3072" && bits != "4096" {
+				return fmt.Errorf("'engineOptions.private_key_bits' must be 2048, 3072 or 4096 for rsa keys")
+			}
+		case keyType == "ec":
+			if bits != "256" && bits != "384" && bits != "521" {
+				return fmt.Errorf("'engineOptions.private_key_bits' must be 256, 384 or 521 for ec keys")
+			}
+		default:
+			return fmt.Errorf("'engineOptions.private_key_bits' is not supported for %s keys", keyType)
+		}
+	}
+
+	if reuse, ok := instance.Spec.EngineOptions["reuse_private_key"]; ok {
+		if _, err := strconv.ParseBool(reuse); err != nil {
+			return fmt.Errorf("'engineOptions.reuse_private_key' must be a boolean")
+		}
+	}
+
+	if keystores := instance.Spec.Keystores; keystores != nil {
+		if keystores.Password != "" && keystores.PasswordSecretRef != nil {
+			return fmt.Errorf("only one of 'keystores.password' or 'keystores.passwordSecretRef' can be set")
+		}
+
+		if keystores.Password == "" && keystores.PasswordSecretRef == nil {
+			return fmt.Errorf("'keystores.password' or 'keystores.passwordSecretRef' must be set")
+		}
+
+		if ref := keystores.PasswordSecretRef; ref != nil && (ref.Name == "" || ref.Key == "") {
+			return fmt.Errorf("'keystores.passwordSecretRef.name' and 'keystores.passwordSecretRef.key' must be set")
+		}
+	}
+
+	return nil
+}
+
+// ValidateDatabase ensures that all fields required by the 'database' secret
+// engine are set.
+func ValidateDatabase(instance *ricobergerdev1alpha1.VaultSecret) error {
+	if instance.Spec.SecretEngine != "database" {
+		return nil
+	}
+
+	if len(instance.Spec.Paths) > 0 {
+		return fmt.Errorf("'paths' is not supported for the 'database' secret engine")
+	}
+
+	if instance.Spec.Role == "" {
+		return fmt.Errorf("'Role' must be set")
+	}
+
+	return nil
+}
+
+// ValidateAWS ensures that all fields required by the 'aws' secret engine are
+// set and that the 'endpoint' engine option is valid.
+func ValidateAWS(instance *ricobergerdev1alpha1.VaultSecret) error {
+	if instance.Spec.SecretEngine != "aws" {
+		return nil
+	}
+
+	if len(instance.Spec.Paths) > 0 {
+		return fmt.Errorf("'paths' is not supported for the 'aws' secret engine")
+	}
+
+	if instance.Spec.Role == "" {
+		return fmt.Errorf("'Role' must be set")
+	}
+
+	if endpoint, ok := instance.Spec.EngineOptions["endpoint"]; ok && endpoint != "creds" && endpoint != "sts" {
+		return fmt.Errorf("'engineOptions.endpoint' must be 'creds' or 'sts'")
+	}
+
+	return nil
+}
+
+// ValidateTransit ensures that all fields required by the 'transit' secret
+// engine are set.
+func ValidateTransit(instance *ricobergerdev1alpha1.VaultSecret) error {
+	if instance.Spec.SecretEngine != "transit" {
+		return nil
+	}
+
+	if len(instance.Spec.Paths) > 0 {
+		return fmt.Errorf("'paths' is not supported for the 'transit' secret engine")
+	}
+
+	if instance.Spec.TransitKey == "" {
+		return fmt.Errorf("'transitKey' must be set")
+	}
+
+	if len(instance.Spec.Ciphertexts) == 0 {
+		return fmt.Errorf("'ciphertexts' must be set")
+	}
+
+	return nil
+}
+
+// ValidateSSH ensures that all fields required by the 'ssh' secret engine are
+// set.
+func ValidateSSH(instance *ricobergerdev1alpha1.VaultSecret) error {
+	if instance.Spec.SecretEngine != "ssh" {
+		return nil
+	}
+
+	if len(instance.Spec.Paths) > 0 {
+		return fmt.Errorf("'paths' is not supported for the 'ssh' secret engine")
+	}
+
+	if instance.Spec.Role == "" {
+		return fmt.Errorf("'Role' must be set")
+	}
+
+	return nil
+}
+
+// ValidateKeystores ensures that the 'keystores' field is only set for the
+// 'pki' secret engine, because the keystores are only created for
+// certificates.
+func ValidateKeystores(instance *ricobergerdev1alpha1.VaultSecret) error {
+	if instance.Spec.Keystores != nil && instance.Spec.SecretEngine != "pki" {
+		return fmt.Errorf("'keystores' is only supported for the 'pki' secret engine")
+	}
+
 	return nil
 }
 
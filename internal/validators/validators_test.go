@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	ricobergerdev1alpha1 "github.com/ricoberger/vault-secrets-operator/api/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
 )
 
 func TestValidatePaths(t *testing.T) {
@@ -44,3 +46,215 @@ func TestValidatePKIWithPaths(t *testing.T) {
 		t.Error("ValidatePKI() expected an error when 'paths' is set for the pki engine")
 	}
 }
+
+func TestValidatePKIEngineOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options map[string]string
+		wantErr bool
+	}{
+		{name: "issue mode", options: map[string]string{"mode": "issue"}, wantErr: false},
+		{name: "sign mode with defaults", options: map[string]string{"mode": "sign"}, wantErr: false},
+		{name: "invalid mode", options: map[string]string{"mode": "generate"}, wantErr: true},
+		{name: "rsa key", options: map[string]string{"mode": "sign", "private_key_type": "rsa", "private_key_bits": "4096"}, wantErr: false},
+		{name: "default key type with bits", options: map[string]string{"mode": "sign", "private_key_bits": "3072"}, wantErr: false},
+		{name: "invalid rsa bits", options: map[string]string{"mode": "sign", "private_key_type": "rsa", "private_key_bits": "1024"}, wantErr: true},
+		{name: "ec key", options: map[string]string{"mode": "sign", "private_key_type": "ec", "private_key_bits": "384"}, wantErr: false},
+		{name: "invalid ec bits", options: map[string]string{"mode": "sign", "private_key_type": "ec", "private_key_bits": "2048"}, wantErr: true},
+		{name: "ed25519 key", options: map[string]string{"mode": "sign", "private_key_type": "ed25519"}, wantErr: false},
+		{name: "ed25519 key with bits", options: map[string]string{"mode": "sign", "private_key_type": "ed25519", "private_key_bits": "256"}, wantErr: true},
+		{name: "invalid key type", options: map[string]string{"mode": "sign", "private_key_type": "dsa"}, wantErr: true},
+		{name: "reuse private key", options: map[string]string{"mode": "sign", "reuse_private_key": "true"}, wantErr: false},
+		{name: "invalid reuse private key", options: map[string]string{"mode": "sign", "reuse_private_key": "yes please"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := &ricobergerdev1alpha1.VaultSecret{}
+			instance.Spec.SecretEngine = "pki"
+			instance.Spec.Role = "example"
+			instance.Spec.EngineOptions = map[string]string{"common_name": "example.com"}
+			for k, v := range tt.options {
+				instance.Spec.EngineOptions[k] = v
+			}
+
+			err := ValidatePKI(instance)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePKI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePKIKeystores(t *testing.T) {
+	tests := []struct {
+		name      string
+		keystores *ricobergerdev1alpha1.VaultSecretKeystores
+		wantErr   bool
+	}{
+		{name: "no keystores", wantErr: false},
+		{name: "password", keystores: &ricobergerdev1alpha1.VaultSecretKeystores{Password: "changeit"}, wantErr: false},
+		{name: "password secret ref", keystores: &ricobergerdev1alpha1.VaultSecretKeystores{PasswordSecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "keystore"}, Key: "password"}}, wantErr: false},
+		{name: "password secret ref without key", keystores: &ricobergerdev1alpha1.VaultSecretKeystores{PasswordSecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "keystore"}}}, wantErr: true},
+		{name: "password and password secret ref", keystores: &ricobergerdev1alpha1.VaultSecretKeystores{Password: "changeit", PasswordSecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "keystore"}, Key: "password"}}, wantErr: true},
+		{name: "no password", keystores: &ricobergerdev1alpha1.VaultSecretKeystores{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := &ricobergerdev1alpha1.VaultSecret{}
+			instance.Spec.SecretEngine = "pki"
+			instance.Spec.Role = "example"
+			instance.Spec.EngineOptions = map[string]string{"common_name": "example.com"}
+			instance.Spec.Keystores = tt.keystores
+
+			err := ValidatePKI(instance)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePKI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDatabase(t *testing.T) {
+	tests := []struct {
+		name    string
+		role    string
+		paths   []string
+		wantErr bool
+	}{
+		{name: "role set", role: "readonly", wantErr: false},
+		{name: "role missing", wantErr: true},
+		{name: "paths set", role: "readonly", paths: []string{"database"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := &ricobergerdev1alpha1.VaultSecret{}
+			instance.Spec.SecretEngine = "database"
+			instance.Spec.Path = "database"
+			instance.Spec.Role = tt.role
+			instance.Spec.Paths = tt.paths
+
+			err := ValidateDatabase(instance)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDatabase() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateAWS(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     string
+		endpoint string
+		wantErr  bool
+	}{
+		{name: "role set", role: "deploy", wantErr: false},
+		{name: "sts endpoint", role: "deploy", endpoint: "sts", wantErr: false},
+		{name: "role missing", wantErr: true},
+		{name: "invalid endpoint", role: "deploy", endpoint: "issue", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := &ricobergerdev1alpha1.VaultSecret{}
+			instance.Spec.SecretEngine = "aws"
+			instance.Spec.Path = "aws"
+			instance.Spec.Role = tt.role
+			if tt.endpoint != "" {
+				instance.Spec.EngineOptions = map[string]string{"endpoint": tt.endpoint}
+			}
+
+			err := ValidateAWS(instance)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateAWS() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateTransit(t *testing.T) {
+	tests := []struct {
+		name        string
+		transitKey  string
+		ciphertexts map[string]string
+		wantErr     bool
+	}{
+		{name: "key and ciphertexts set", transitKey: "my-key", ciphertexts: map[string]string{"password": "vault:v1:abc"}, wantErr: false},
+		{name: "key missing", ciphertexts: map[string]string{"password": "vault:v1:abc"}, wantErr: true},
+		{name: "ciphertexts missing", transitKey: "my-key", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := &ricobergerdev1alpha1.VaultSecret{}
+			instance.Spec.SecretEngine = "transit"
+			instance.Spec.Path = "transit"
+			instance.Spec.TransitKey = tt.transitKey
+			instance.Spec.Ciphertexts = tt.ciphertexts
+
+			err := ValidateTransit(instance)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTransit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateSSH(t *testing.T) {
+	tests := []struct {
+		name    string
+		role    string
+		paths   []string
+		wantErr bool
+	}{
+		{name: "role set", role: "client", wantErr: false},
+		{name: "role missing", wantErr: true},
+		{name: "paths set", role: "client", paths: []string{"ssh-client-signer"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := &ricobergerdev1alpha1.VaultSecret{}
+			instance.Spec.SecretEngine = "ssh"
+			instance.Spec.Path = "ssh-client-signer"
+			instance.Spec.Role = tt.role
+			instance.Spec.Paths = tt.paths
+
+			err := ValidateSSH(instance)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSSH() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateKeystores(t *testing.T) {
+	tests := []struct {
+		name         string
+		secretEngine string
+		keystores    *ricobergerdev1alpha1.VaultSecretKeystores
+		wantErr      bool
+	}{
+		{name: "pki with keystores", secretEngine: "pki", keystores: &ricobergerdev1alpha1.VaultSecretKeystores{Password: "changeit"}, wantErr: false},
+		{name: "kv without keystores", secretEngine: "kv", wantErr: false},
+		{name: "kv with keystores", secretEngine: "kv", keystores: &ricobergerdev1alpha1.VaultSecretKeystores{Password: "changeit"}, wantErr: true},
+		{name: "default engine with keystores", keystores: &ricobergerdev1alpha1.VaultSecretKeystores{Password: "changeit"}, wantErr: true},
+		{name: "database with keystores", secretEngine: "database", keystores: &ricobergerdev1alpha1.VaultSecretKeystores{Password: "changeit"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := &ricobergerdev1alpha1.VaultSecret{}
+			instance.Spec.SecretEngine = tt.secretEngine
+			instance.Spec.Keystores = tt.keystores
+
+			err := ValidateKeystores(instance)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateKeystores() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"os"
+	"time"
 
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/hashicorp/vault/api"
@@ -84,7 +85,9 @@ func awsLogin(ctx context.Context, client *api.Client, cfg awsAuthConfig) (*api.
 		return nil, err
 	}
 
+	start := time.Now()
 	secret, err := awsAuth.Login(ctx, client)
+	observeRequest(requestOperationLogin, "auth/"+cfg.mountPath, start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,83 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ricoberger/vault-secrets-operator/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// GetAWSCredentials returns AWS credentials for the given role of the AWS
+// secrets engine mounted under the given path. By default the credentials are
+// generated via the "creds" endpoint. The "endpoint" option can be set to "sts"
+// to use the "sts" endpoint instead. All other options (e.g. "ttl", "role_arn"
+// and "role_session_name") are passed to Vault. Next to the credentials the
+// lease of the credentials is returned.
+func (c *Client) GetAWSCredentials(ctx context.Context, path string, role string, options map[string]string, vaultNamespace string) (_ map[string][]byte, _ *Lease, err error) {
+	endpoint := "creds"
+	optionsI := make(map[string]any, len(options))
+	for k, v := range options {
+		if k == "endpoint" {
+			endpoint = v
+			continue
+		}
+		optionsI[k] = v
+	}
+
+	if endpoint != "creds" && endpoint != "sts" {
+		return nil, nil, fmt.Errorf("invalid aws endpoint %q", endpoint)
+	}
+
+	log.Info(fmt.Sprintf("Read aws credentials %s/%s/%s", path, endpoint, role))
+
+	ctx, span := tracer.Start(ctx, "GetAWSCredentials", trace.WithAttributes(attribute.String("vault.path", path), attribute.String("vault.role", role), attribute.String("vault.endpoint", endpoint)))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	client, err := c.namespacedClient(vaultNamespace)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	start := time.Now()
+	r, err := client.Logical().WriteWithContext(ctx, path+"/"+endpoint+"/"+role, optionsI)
+	observeRequest(requestOperationAWSCreds, path, start, err)
+	setRequestID(ctx, r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if r == nil {
+		return nil, nil, fmt.Errorf("credentials are nil")
+	}
+
+	accessKey, _ := r.Data["access_key"].(string)
+	secretKey, _ := r.Data["secret_key"].(string)
+	if accessKey == "" || secretKey == "" {
+		return nil, nil, fmt.Errorf("invalid credentials data")
+	}
+
+	data := map[string][]byte{
+		"AWS_ACCESS_KEY_ID":     []byte(accessKey),
+		"AWS_SECRET_ACCESS_KEY": []byte(secretKey),
+	}
+
+	// Newer Vault versions return the session token as "session_token", older
+	// versions as "security_token". The session token is only returned for
+	// the "assumed_role", "federation_token" and "session_token" credential
+	// types.
+	sessionToken, _ := r.Data["session_token"].(string)
+	if sessionToken == "" {
+		sessionToken, _ = r.Data["security_token"].(string)
+	}
+	if sessionToken != "" {
+		data["AWS_SESSION_TOKEN"] = []byte(sessionToken)
+	}
+
+	lease := newLease(r)
+	c.leaseObtained(lease)
+	return data, lease, nil
+}
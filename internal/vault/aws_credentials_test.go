@@ -0,0 +1,127 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestGetAWSCredentials verifies that the credentials returned by the AWS
+// secrets engine are mapped to the AWS environment variable names and that the
+// engine options are passed to Vault.
+func TestGetAWSCredentials(t *testing.T) {
+	var (
+		gotPath string
+		gotBody map[string]any
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"lease_id": "aws/sts/deploy/b2f5a3c1",
+			"lease_duration": 900,
+			"renewable": false,
+			"data": {
+				"access_key": "ASIAEXAMPLE",
+				"secret_key": "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY",
+				"security_token": "FwoGZXIvYXdzEXAMPLE"
+			}
+		}`))
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv.URL)
+
+	data, lease, err := client.GetAWSCredentials(context.Background(), "aws", "deploy", map[string]string{
+		"endpoint":          "sts",
+		"ttl":               "15m",
+		"role_session_name": "batch-job",
+	}, "")
+	if err != nil {
+		t.Fatalf("GetAWSCredentials returned an error: %v", err)
+	}
+
+	if gotPath != "/v1/aws/sts/deploy" {
+		t.Errorf("path = %q, want /v1/aws/sts/deploy", gotPath)
+	}
+	if _, ok := gotBody["endpoint"]; ok {
+		t.Error("endpoint option must not be passed to Vault")
+	}
+	if got := gotBody["ttl"]; got != "15m" {
+		t.Errorf("ttl = %v, want 15m", got)
+	}
+	if got := gotBody["role_session_name"]; got != "batch-job" {
+		t.Errorf("role_session_name = %v, want batch-job", got)
+	}
+
+	want := map[string]string{
+		"AWS_ACCESS_KEY_ID":     "ASIAEXAMPLE",
+		"AWS_SECRET_ACCESS_KEY": "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY",
+		"AWS_SESSION_TOKEN":     "FwoGZXIvYXdzEXAMPLE",
+	}
+	if len(data) != len(want) {
+		t.Fatalf("got %d keys, want %d: %v", len(data), len(want), data)
+	}
+	for k, v := range want {
+		if got := string(data[k]); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+
+	if lease == nil || lease.Duration != 15*time.Minute || lease.Renewable {
+		t.Errorf("unexpected lease: %+v", lease)
+	}
+}
+
+// TestGetAWSCredentialsIAMUser verifies that no session token is written for
+// IAM user credentials and that the creds endpoint is used by default.
+func TestGetAWSCredentialsIAMUser(t *testing.T) {
+	var gotPath string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"lease_id": "aws/creds/deploy/f3e92392",
+			"lease_duration": 3600,
+			"renewable": true,
+			"data": {
+				"access_key": "AKIAEXAMPLE",
+				"secret_key": "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY",
+				"security_token": null
+			}
+		}`))
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv.URL)
+
+	data, _, err := client.GetAWSCredentials(context.Background(), "aws", "deploy", nil, "")
+	if err != nil {
+		t.Fatalf("GetAWSCredentials returned an error: %v", err)
+	}
+
+	if gotPath != "/v1/aws/creds/deploy" {
+		t.Errorf("path = %q, want /v1/aws/creds/deploy", gotPath)
+	}
+	if _, ok := data["AWS_SESSION_TOKEN"]; ok {
+		t.Error("expected no AWS_SESSION_TOKEN for IAM user credentials")
+	}
+}
+
+// TestGetAWSCredentialsNamespace verifies that the namespace of a secret is used
+// to request the credentials.
+func TestGetAWSCredentialsNamespace(t *testing.T) {
+	client, namespaces := newTestNamespaceClient(t, `{"lease_id": "aws/creds/deploy/1234", "lease_duration": 3600, "data": {"access_key": "AKIA", "secret_key": "secret"}}`)
+
+	if _, _, err := client.GetAWSCredentials(context.Background(), "aws", "deploy", nil, "team-a"); err != nil {
+		t.Fatalf("GetAWSCredentials returned an error: %v", err)
+	}
+
+	assertNamespaces(t, namespaces())
+}
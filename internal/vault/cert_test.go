@@ -0,0 +1,184 @@
+package vault
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestCreateClientCertAuth verifies that the operator authenticates with the
+// client certificate when the cert auth method is used and that a new token is
+// requested with the rotated certificate, when the certificate files change.
+func TestCreateClientCertAuth(t *testing.T) {
+	ca := newTestCertificate(t, "vault-ca", nil, &x509.Certificate{})
+	server := newTestCertificate(t, "vault.example.com", ca, &x509.Certificate{
+		DNSNames:    []string{"vault.example.com"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	first := newTestCertificate(t, "first", ca, &x509.Certificate{
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	second := newTestCertificate(t, "second", ca, &x509.Certificate{
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	serverCert, err := tls.X509KeyPair(server.certPEM, server.keyPEM)
+	if err != nil {
+		t.Fatalf("failed to load server certificate: %v", err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/cert/login" {
+			http.NotFound(w, r)
+			return
+		}
+
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if body["name"] != "web" {
+			http.Error(w, "unexpected role", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"auth": {"client_token": "token-%s", "lease_duration": 3600, "renewable": true}}`, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+
+	mtime := time.Now().Add(-time.Minute)
+	writeTestFile(t, caFile, ca.certPEM, mtime)
+	writeTestFile(t, certFile, first.certPEM, mtime)
+	writeTestFile(t, keyFile, first.keyPEM, mtime)
+
+	t.Setenv("VAULT_ADDRESS", srv.URL)
+	t.Setenv("VAULT_AUTH_METHOD", "cert")
+	t.Setenv("VAULT_CERT_ROLE", "web")
+	t.Setenv("VAULT_TLS_CA_CERT", caFile)
+	t.Setenv("VAULT_TLS_CLIENT_CERT", certFile)
+	t.Setenv("VAULT_TLS_CLIENT_KEY", keyFile)
+	t.Setenv("VAULT_TLS_SERVER_NAME", "vault.example.com")
+
+	client, err := CreateClient("")
+	if err != nil {
+		t.Fatalf("CreateClient returned an error: %v", err)
+	}
+	if token := client.client.Token(); token != "token-first" {
+		t.Errorf("token = %q, want token-first", token)
+	}
+	if interval := client.getTokenRenewalInterval(); interval != 30*time.Minute {
+		t.Errorf("renewal interval = %s, want 30m", interval)
+	}
+	if client.certFiles.changed() {
+		t.Error("expected the certificate files to be unchanged")
+	}
+
+	mtime = time.Now()
+	writeTestFile(t, certFile, second.certPEM, mtime)
+	writeTestFile(t, keyFile, second.keyPEM, mtime)
+	if !client.certFiles.changed() {
+		t.Fatal("expected the certificate files to be changed")
+	}
+
+	if err := client.requestToken(client); err != nil {
+		t.Fatalf("requestToken returned an error: %v", err)
+	}
+	if token := client.client.Token(); token != "token-second" {
+		t.Errorf("token = %q, want token-second", token)
+	}
+	if client.certFiles.changed() {
+		t.Error("expected the certificate files to be unchanged after the new token was requested")
+	}
+}
+
+// TestCreateClientCertAuthMissingCertificate verifies that the cert auth method
+// can not be used without a client certificate.
+func TestCreateClientCertAuthMissingCertificate(t *testing.T) {
+	t.Setenv("VAULT_ADDRESS", "https://vault.example.com")
+	t.Setenv("VAULT_AUTH_METHOD", "cert")
+	t.Setenv("VAULT_TLS_CLIENT_CERT", "")
+	t.Setenv("VAULT_TLS_CLIENT_KEY", "")
+
+	if _, err := CreateClient(""); err == nil {
+		t.Fatal("expected an error for a missing client certificate")
+	}
+}
+
+// TestClientWaitTokenRenewalCertFilesChanged verifies that the wait between two
+// token renewals ends, when the files of the client certificate were changed.
+func TestClientWaitTokenRenewalCertFilesChanged(t *testing.T) {
+	ca := newTestCertificate(t, "vault-ca", nil, &x509.Certificate{})
+	first := newTestCertificate(t, "first", ca, &x509.Certificate{})
+	second := newTestCertificate(t, "second", ca, &x509.Certificate{})
+
+	dir := t.TempDir()
+	files := &tlsFiles{clientCertFile: filepath.Join(dir, "tls.crt"), clientKeyFile: filepath.Join(dir, "tls.key")}
+
+	mtime := time.Now().Add(-time.Minute)
+	writeTestFile(t, files.clientCertFile, first.certPEM, mtime)
+	writeTestFile(t, files.clientKeyFile, first.keyPEM, mtime)
+	if _, err := files.getClientCertificate(nil); err != nil {
+		t.Fatalf("getClientCertificate returned an error: %v", err)
+	}
+
+	interval := certFilesCheckInterval
+	certFilesCheckInterval = 10 * time.Millisecond
+	defer func() { certFilesCheckInterval = interval }()
+
+	client := &Client{certFiles: files}
+
+	started := time.Now()
+	if !client.waitTokenRenewal(context.Background(), 50*time.Millisecond) {
+		t.Error("expected the token to be renewed")
+	}
+	if elapsed := time.Since(started); elapsed < 50*time.Millisecond {
+		t.Errorf("wait returned after %s, want at least 50ms", elapsed)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		time.Sleep(20 * time.Millisecond)
+		mtime := time.Now()
+		for name, data := range map[string][]byte{files.clientCertFile: second.certPEM, files.clientKeyFile: second.keyPEM} {
+			if err := os.WriteFile(name, data, 0o600); err != nil {
+				t.Errorf("failed to write %s: %v", name, err)
+			}
+			if err := os.Chtimes(name, mtime, mtime); err != nil {
+				t.Errorf("failed to set modification time of %s: %v", name, err)
+			}
+		}
+	}()
+
+	started = time.Now()
+	if !client.waitTokenRenewal(context.Background(), time.Minute) {
+		t.Error("expected the token to be renewed")
+	}
+	if elapsed := time.Since(started); elapsed > 10*time.Second {
+		t.Errorf("wait returned after %s, want it to end when the files change", elapsed)
+	}
+	<-done
+}
@@ -1,17 +1,24 @@
 package vault
 
 import (
+	"context"
 	b64 "encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"path"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/ricoberger/vault-secrets-operator/internal/tracing"
+
 	"github.com/hashicorp/vault/api"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // RequestToken is a function to request a new Vault token, specific for auth
@@ -20,7 +27,14 @@ type RequestToken func(*Client) error
 
 // Client is the structure of our global client for Vault.
 type Client struct {
-	// client is the API client for requests against Vault.
+	// mu protects the token state of the client (tokenLeaseDuration,
+	// tokenRenewalInterval, the failed renew token attempts and
+	// leaseExpireTime), so that the client can be used by multiple reconcile
+	// workers and the token renewal in parallel.
+	mu sync.RWMutex
+	// client is the API client for requests against Vault. The API client is
+	// safe for concurrent use and must not be modified per request (e.g. by
+	// setting a namespace), because it is shared by all reconcile workers.
 	client *api.Client
 	// tokenLeaseDuration is the lease duration of the token for the interaction
 	// with Vault.
@@ -47,12 +61,37 @@ type Client struct {
 	// restrict the operator to the Vault namespace set in the
 	// rootVaultNamespace field
 	restrictNamespace bool
-	// failedRenewTokenAttempts is the number of failed renew token attempts, if
-	// the renew token function fails 5 times the liveness probe will fail, to
-	// force a restart of the operator.
+	// failedRenewTokenAttempts is the number of consecutive failed renew token
+	// attempts.
 	failedRenewTokenAttempts int
+	// firstFailedRenewTokenAttempt is the time of the first of the consecutive
+	// failed renew token attempts. If the token could not be renewed for a
+	// longer time, the health checks fail to force a restart of the operator.
+	firstFailedRenewTokenAttempt time.Time
 	// pkiRenew minimum remaining period of validity before certificate renewal
 	pkiRenew time.Duration
+	// leaseRenew minimum remaining period of validity before a dynamic secret
+	// is renewed
+	leaseRenew time.Duration
+	// kvMounts caches the mount path and version of the KV secrets engines.
+	kvMounts *kvMountCache
+	// certFiles are the files of the client certificate, which is used for
+	// the cert auth method. A new token is requested when the files change.
+	certFiles *tlsFiles
+	// authMethod is the auth method, which is used by the client to request a
+	// new token.
+	authMethod string
+	// tokenExpireTime is the time when the current token expires, based on
+	// the TTL returned by the last login or renewal.
+	tokenExpireTime time.Time
+	// tokenRenewTime is the time of the last successful login or renewal of
+	// the token.
+	tokenRenewTime time.Time
+	// leaseExpireTime is the latest expiration time of the leases, which were
+	// created or renewed with the client. Vault revokes all leases of a token
+	// together with the token, so that the token must not be revoked before
+	// this time.
+	leaseExpireTime time.Time
 }
 
 // PerformRenewToken returns whether the operator should renew its token
@@ -60,54 +99,111 @@ func (c *Client) PerformRenewToken() bool {
 	return c.renewToken
 }
 
-// RenewToken renews the provided token after the half of the lease duration is
-// passed, retrying every 30 seconds in case of errors.
-func (c *Client) RenewToken() {
-	started := time.Now()
-	for {
-		// Set the namespace to the value from the VAULT_NAMESPACE environment
-		// variable, because the namespace will always change, when a secret is
-		// requested.
-		if c.rootVaultNamespace != "" {
-			c.client.SetNamespace(c.rootVaultNamespace)
-		}
+// setToken sets the token from the given authentication information, which
+// was returned by a login request, for the API client and updates the lease
+// duration of the token. The renewal interval is set to the given value or to
+// the half of the lease duration if the value is not a valid number.
+func (c *Client) setToken(auth *api.SecretAuth, renewalInterval string) error {
+	if auth == nil {
+		return fmt.Errorf("missing authentication information")
+	}
 
-		// Request a new token if the actual token lifetime more than the
-		// specified maximum lifetime.
-		elapsed := time.Since(started).Seconds()
-		if c.tokenMaxTTL > 0 && elapsed >= float64(c.tokenMaxTTL) && c.requestToken != nil {
-			log.Info("Request new Vault token")
-			err := c.requestToken(c)
-			if err != nil {
-				log.Error(err, "Could not request a new token")
-				c.failedRenewTokenAttempts = c.failedRenewTokenAttempts + 1
-				time.Sleep(time.Duration(c.tokenRenewalRetryInterval) * time.Second)
-			} else {
-				c.failedRenewTokenAttempts = 0
-				started = time.Now()
-				time.Sleep(time.Duration(c.tokenRenewalInterval) * time.Second)
-			}
-			continue
-		}
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-		log.Info("Renew Vault token")
-		_, err := c.client.Auth().Token().RenewSelf(c.tokenLeaseDuration)
-		if err != nil {
-			log.Error(err, "Could not renew token")
-			c.failedRenewTokenAttempts = c.failedRenewTokenAttempts + 1
-			time.Sleep(time.Duration(c.tokenRenewalRetryInterval) * time.Second)
-		} else {
-			c.failedRenewTokenAttempts = 0
-			time.Sleep(time.Duration(c.tokenRenewalInterval) * time.Second)
-		}
+	c.client.SetToken(auth.ClientToken)
+	c.tokenLeaseDuration = auth.LeaseDuration
+	c.tokenRenewTime = time.Now()
+	c.tokenExpireTime = c.tokenRenewTime.Add(time.Duration(auth.LeaseDuration) * time.Second)
+
+	tokenRenewalInterval, err := strconv.ParseFloat(renewalInterval, 64)
+	if err != nil {
+		tokenRenewalInterval = float64(auth.LeaseDuration) * 0.5
 	}
+	c.tokenRenewalInterval = tokenRenewalInterval
+
+	return nil
 }
 
-// GetHealth checks if the failedRenewTokenAttempts hits the given thresholds.
-// If this is the case an error is returned.
-func (c *Client) GetHealth(threshold int) error {
-	if c.failedRenewTokenAttempts >= threshold {
-		return fmt.Errorf("renew Vault token failed %d times", c.failedRenewTokenAttempts)
+// tokenRenewed updates the expiration time of the token with the TTL, which was
+// returned by a renewal of the token.
+func (c *Client) tokenRenewed(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.tokenRenewTime = time.Now()
+	c.tokenExpireTime = c.tokenRenewTime.Add(ttl)
+}
+
+// tokenStatus returns the expiration time of the token, the time of the last
+// successful login or renewal and the number of failed renew token attempts.
+func (c *Client) tokenStatus() (time.Time, time.Time, int) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.tokenExpireTime, c.tokenRenewTime, c.failedRenewTokenAttempts
+}
+
+// revokeToken revokes the token of the client.
+func (c *Client) revokeToken() error {
+	start := time.Now()
+	err := c.client.Auth().Token().RevokeSelf("")
+	observeRequest(requestOperationRevoke, "auth/token", start, err)
+	return err
+}
+
+// getTokenLeaseDuration returns the lease duration of the current token in
+// seconds.
+func (c *Client) getTokenLeaseDuration() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.tokenLeaseDuration
+}
+
+// getTokenRenewalInterval returns the time between two successive token
+// renewals.
+func (c *Client) getTokenRenewalInterval() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return time.Duration(c.tokenRenewalInterval) * time.Second
+}
+
+// renewTokenFailed increases the number of failed renew token attempts and
+// returns the new number of failed attempts.
+func (c *Client) renewTokenFailed() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.failedRenewTokenAttempts == 0 {
+		c.firstFailedRenewTokenAttempt = time.Now()
+	}
+	c.failedRenewTokenAttempts = c.failedRenewTokenAttempts + 1
+	return c.failedRenewTokenAttempts
+}
+
+// renewTokenSucceeded resets the failed renew token attempts.
+func (c *Client) renewTokenSucceeded() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.failedRenewTokenAttempts = 0
+	c.firstFailedRenewTokenAttempt = time.Time{}
+}
+
+// GetHealth checks if the renewal of the token is failing for at least the
+// given threshold. If this is the case an error is returned. The threshold is
+// a duration and not a number of attempts, because the time between two
+// attempts grows with the backoff of the renewal.
+func (c *Client) GetHealth(threshold time.Duration) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.failedRenewTokenAttempts > 0 {
+		if failing := time.Since(c.firstFailedRenewTokenAttempt); failing >= threshold {
+			return fmt.Errorf("renew Vault token failed %d times in %s", c.failedRenewTokenAttempts, failing.Round(time.Second))
+		}
 	}
 
 	return nil
@@ -118,36 +214,64 @@ func (c *Client) IsNamespaceRestricted() (bool, string) {
 }
 
 // GetSecret returns the value for a given secret.
-func (c *Client) GetSecret(path string, keys []string, version int, isBinary bool, vaultNamespace string) (map[string][]byte, error) {
+func (c *Client) GetSecret(ctx context.Context, path string, keys []string, version int, isBinary bool, vaultNamespace string) (_ map[string][]byte, err error) {
 	// Get the secret for the given path and return the secret data.
 	log.Info(fmt.Sprintf("Read secret %s", path))
 
-	// Check if the vaultNamespace field is set for the secret. If the field is
-	// set we use the configured root namespace from the VAULT_NAMESPACE and
-	// the value from the vaultNamespace field to build the final namespace
-	// path. If the field is not set but VAULT_NAMESPACE has a value, we
-	// just use the latter.
-	// If the vaultNamespace field is set, but not the VAULT_NAMESPACE
-	// environment variable we return an error, because the authentication
-	// already failed.
-	if c.rootVaultNamespace != "" {
-		log.WithValues("rootVaultNamespace", c.rootVaultNamespace, "vaultNamespace", vaultNamespace).Info(fmt.Sprintf("Use Vault Namespace to read secret %s", path))
-		if vaultNamespace != "" && !c.restrictNamespace {
-			c.client.SetNamespace(c.rootVaultNamespace + "/" + vaultNamespace)
-		} else {
-			c.client.SetNamespace(c.rootVaultNamespace)
-		}
-	} else if c.rootVaultNamespace == "" && vaultNamespace != "" {
-		return nil, fmt.Errorf("vaultNamespace field can not be used, because the VAULT_NAMESPACE environment variable is not set")
+	ctx, span := tracer.Start(ctx, "GetSecret", trace.WithAttributes(attribute.String("vault.path", path)))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	client, err := c.namespacedClient(vaultNamespace)
+	if err != nil {
+		return nil, err
 	}
 
 	// Check if the KVv1 or KVv2 is used for the provided secret and determin
 	// the mount path of the secrets engine.
-	mountPath, v2, err := c.isKVv2(path)
+	mountPath, v2, err := c.isKVv2(client, path)
 	if err != nil {
 		return nil, err
 	}
 
+	span.SetAttributes(attribute.String("vault.mount", mountPath), attribute.Bool("vault.kv_v2", v2))
+
+	data, err := c.readSecret(ctx, client, path, mountPath, v2, keys, version, isBinary)
+	if err != nil {
+		// The cached mount information might be outdated, e.g. because the
+		// secrets engine was upgraded from KVv1 to KVv2, so that we remove it
+		// from the cache and run the preflight request again for the next
+		// read. This is only done for responses which indicate a wrong mount,
+		// so that other errors (e.g. permission denied or an unreachable
+		// Vault) do not cause a preflight request for every read.
+		var mountErr *kvMountError
+		if errors.As(err, &mountErr) {
+			c.kvMounts.invalidate(client.Namespace(), mountPath)
+		}
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// kvMountError is returned by readSecret, when the response of Vault indicates
+// that the mount path or the version of the KV secrets engine is wrong, e.g.
+// because the secret was not found or the secrets engine was upgraded from KVv1
+// to KVv2.
+type kvMountError struct {
+	err error
+}
+
+func (e *kvMountError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kvMountError) Unwrap() error {
+	return e.err
+}
+
+// readSecret reads the secret for the given path from the KV secrets engine
+// mounted under the given mount path.
+func (c *Client) readSecret(ctx context.Context, client *api.Client, path, mountPath string, v2 bool, keys []string, version int, isBinary bool) (map[string][]byte, error) {
 	// If the KVv2 secrets engine is used we add the 'data' prefix to the
 	// secrets path. If a version is provided we fill the request data with the
 	// version parameter.
@@ -163,13 +287,29 @@ func (c *Client) GetSecret(path string, keys []string, version int, isBinary boo
 		}
 	}
 
-	secret, err := c.client.Logical().ReadWithData(path, reqData)
+	start := time.Now()
+	secret, err := client.Logical().ReadWithDataWithContext(ctx, path, reqData)
+	observeRequest(requestOperationKVRead, mountPath, start, err)
+	setRequestID(ctx, secret)
 	if err != nil {
+		var respErr *api.ResponseError
+		if errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound {
+			return nil, &kvMountError{err: err}
+		}
 		return nil, err
 	}
 
+	// Vault returns no secret, when the path was not found.
 	if secret == nil {
-		return nil, fmt.Errorf("secret is nil")
+		return nil, &kvMountError{err: fmt.Errorf("secret is nil")}
+	}
+
+	// A KVv2 secrets engine returns this warning, when it is read like a KVv1
+	// secrets engine.
+	for _, warning := range secret.Warnings {
+		if strings.HasPrefix(warning, "Invalid path for a versioned K/V secrets engine") {
+			return nil, &kvMountError{err: fmt.Errorf("invalid secret data: %s", warning)}
+		}
 	}
 
 	// The structure for a KVv2 secret differs from the structure of a KV1
@@ -180,7 +320,7 @@ func (c *Client) GetSecret(path string, keys []string, version int, isBinary boo
 		var ok bool
 		secretData, ok = secret.Data["data"].(map[string]any)
 		if !ok {
-			return nil, fmt.Errorf("could not parse secret")
+			return nil, &kvMountError{err: fmt.Errorf("could not parse secret")}
 		}
 	}
 
@@ -189,16 +329,43 @@ func (c *Client) GetSecret(path string, keys []string, version int, isBinary boo
 		return nil, err
 	}
 
-	// If the data map is empty we return an error. This can happened, if the
-	// secret which was retrieved from Vault is under a KVv2 secrets engine, but
-	// the secret engine was not provided in the cr for the secret. Then the
-	// returned secret looks like this: &api.Secret{RequestID:\"be7b671f-a097-1081-15ec-b4710f2a6249\", LeaseID:\"\", LeaseDuration:0, Renewable:false, Data:map[string]interface {}(nil), Warnings:[]string{\"Invalid path for a versioned K/V secrets engine. See the API docs for the appropriate API endpoints to use. If using the Vault CLI, use 'vault kv get' for this operation.\"}, Auth:(*api.SecretAuth)(nil), WrapInfo:(*api.SecretWrapInfo)(nil)}"}
+	// If the data map is empty we return an error, e.g. because the secret
+	// does not contain any of the given keys.
 	if len(data) == 0 {
 		return nil, fmt.Errorf("invalid secret data")
 	}
 	return data, nil
 }
 
+// namespacedClient returns the Vault API client, which should be used for all
+// requests of a secret with the given vaultNamespace, for every secrets engine.
+// If the vaultNamespace field is set for the secret, we use the configured root
+// namespace from the VAULT_NAMESPACE and the value from the vaultNamespace
+// field to build the final namespace path. If the field is not set but
+// VAULT_NAMESPACE has a value, we just use the latter. If the vaultNamespace
+// field is set, but not the VAULT_NAMESPACE environment variable we return an
+// error, because the authentication already failed.
+//
+// The namespace is set on a shallow copy of the API client, so that it only
+// applies to the requests for this secret. The shared API client is never
+// modified, because it is used concurrently by other reconciliations and the
+// token renewal. The copy is also returned when no namespace is used, because
+// the KV preflight request temporarily changes the settings of the client.
+func (c *Client) namespacedClient(vaultNamespace string) (*api.Client, error) {
+	if c.rootVaultNamespace == "" {
+		if vaultNamespace != "" {
+			return nil, fmt.Errorf("vaultNamespace field can not be used, because the VAULT_NAMESPACE environment variable is not set")
+		}
+		return c.client.WithNamespace(""), nil
+	}
+
+	log.WithValues("rootVaultNamespace", c.rootVaultNamespace, "vaultNamespace", vaultNamespace).Info("Use Vault Namespace for request")
+	if vaultNamespace != "" && !c.restrictNamespace {
+		return c.client.WithNamespace(c.rootVaultNamespace + "/" + vaultNamespace), nil
+	}
+	return c.client.WithNamespace(c.rootVaultNamespace), nil
+}
+
 // Convert the secret data for a Kubernetes secret. We only add the provided
 // keys to the resulting data or if there are no keys provided we add all
 // keys of the secret.
@@ -247,17 +414,19 @@ func convertData(secretData map[string]any, keys []string, isBinary bool) (map[s
 // engine is used for the given path.
 // This function is copy/past from the github.com/hashicorp/vault repository,
 // see: https://github.com/hashicorp/vault/blob/f843c09dd15ca4982e60fa12dea48c8f7d7e0373/command/kv_helpers.go#L44
-func (c *Client) kvPreflightVersionRequest(path string) (string, int, error) {
+func (c *Client) kvPreflightVersionRequest(client *api.Client, path string) (string, int, error) {
 	// We don't want to use a wrapping call here so save any custom value and
 	// restore after
-	currentWrappingLookupFunc := c.client.CurrentWrappingLookupFunc()
-	c.client.SetWrappingLookupFunc(nil)
-	defer c.client.SetWrappingLookupFunc(currentWrappingLookupFunc)
-	currentOutputCurlString := c.client.OutputCurlString()
-	c.client.SetOutputCurlString(false)
-	defer c.client.SetOutputCurlString(currentOutputCurlString)
-
-	resp, err := c.client.Logical().ReadRaw("sys/internal/ui/mounts/" + path)
+	currentWrappingLookupFunc := client.CurrentWrappingLookupFunc()
+	client.SetWrappingLookupFunc(nil)
+	defer client.SetWrappingLookupFunc(currentWrappingLookupFunc)
+	currentOutputCurlString := client.OutputCurlString()
+	client.SetOutputCurlString(false)
+	defer client.SetOutputCurlString(currentOutputCurlString)
+
+	start := time.Now()
+	resp, err := client.Logical().ReadRaw("sys/internal/ui/mounts/" + path)
+	observeRequest(requestOperationPreflight, "sys/internal/ui/mounts", start, err)
 	if resp != nil {
 		defer resp.Body.Close()
 	}
@@ -302,15 +471,22 @@ func (c *Client) kvPreflightVersionRequest(path string) (string, int, error) {
 }
 
 // isKVv2 returns true if a KVv2 is used for the given path and false if a KVv1
-// secret engine is used.
+// secret engine is used. The mount information is taken from the cache, if
+// available.
 // This function is copy/past from the github.com/hashicorp/vault repository,
 // see: https://github.com/hashicorp/vault/blob/f843c09dd15ca4982e60fa12dea48c8f7d7e0373/command/kv_helpers.go#L99
-func (c *Client) isKVv2(path string) (string, bool, error) {
-	mountPath, version, err := c.kvPreflightVersionRequest(path)
+func (c *Client) isKVv2(client *api.Client, path string) (string, bool, error) {
+	if mountPath, version, ok := c.kvMounts.get(client.Namespace(), path); ok {
+		return mountPath, version == 2, nil
+	}
+
+	mountPath, version, err := c.kvPreflightVersionRequest(client, path)
 	if err != nil {
 		return "", false, err
 	}
 
+	c.kvMounts.set(client.Namespace(), mountPath, version)
+
 	return mountPath, version == 2, nil
 }
 
@@ -330,3 +506,55 @@ func (c *Client) addPrefixToVKVPath(p, mountPath, apiPrefix string) string {
 func (c *Client) GetPKIRenew() time.Duration {
 	return c.pkiRenew
 }
+
+// IsPermanentError returns true, when Vault rejected a request because the
+// object does not exist or the request is invalid (e.g. "lease not found",
+// "invalid lease ID" or an unknown certificate), so that the request will also
+// fail when it is retried. All other errors are not permanent. This includes
+// permission denied errors, because Vault also returns them for an expired or
+// revoked token and for a temporary misconfiguration of a policy.
+func IsPermanentError(err error) bool {
+	var respErr *api.ResponseError
+	if !errors.As(err, &respErr) {
+		return false
+	}
+
+	return respErr.StatusCode == http.StatusBadRequest || respErr.StatusCode == http.StatusNotFound
+}
+
+// isPermissionDenied returns true, when Vault rejected a request with
+// permission denied.
+func isPermissionDenied(err error) bool {
+	var respErr *api.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusForbidden
+}
+
+// withRelogin runs the given request and repeats it once with a new token, when
+// Vault rejected the request with permission denied, e.g. because the token of
+// the client expired or was revoked. The request must create the API client
+// for the namespace on every call, so that the new token is used.
+func (c *Client) withRelogin(request func() error) error {
+	err := request()
+	if !isPermissionDenied(err) || c.requestToken == nil {
+		return err
+	}
+
+	log.Error(err, "Request was denied, request new Vault token and retry")
+	if loginErr := c.relogin(); loginErr != nil {
+		log.Error(loginErr, "Could not request new Vault token")
+		return err
+	}
+
+	return request()
+}
+
+// relogin requests a new token for the client via the auth method of the
+// client.
+func (c *Client) relogin() error {
+	if err := c.requestToken(c); err != nil {
+		tokenLoginsTotal.WithLabelValues(c.authMethod, loginStatusFailure).Inc()
+		return err
+	}
+	tokenLoginsTotal.WithLabelValues(c.authMethod, loginStatusSuccess).Inc()
+	return nil
+}
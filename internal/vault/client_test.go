@@ -0,0 +1,238 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// TestGetSecretNamespace verifies that the namespace of a secret is sent with
+// all requests to read the secret, without changing the namespace of the
+// shared API client.
+func TestGetSecretNamespace(t *testing.T) {
+	var (
+		mu         sync.Mutex
+		namespaces = map[string]string{}
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		namespaces[r.URL.Path] = r.Header.Get("X-Vault-Namespace")
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasPrefix(r.URL.Path, "/v1/sys/internal/ui/mounts/") {
+			_, _ = w.Write([]byte(`{"data": {"path": "kv/", "options": null}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"username": "admin"}}`))
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv.URL)
+	client.client.SetNamespace("root")
+	client.rootVaultNamespace = "root"
+
+	data, err := client.GetSecret(context.Background(), "kv/team", nil, 0, false, "team-a")
+	if err != nil {
+		t.Fatalf("GetSecret returned an error: %v", err)
+	}
+	if string(data["username"]) != "admin" {
+		t.Errorf("username = %q, want admin", data["username"])
+	}
+
+	for path, namespace := range namespaces {
+		if namespace != "root/team-a" {
+			t.Errorf("namespace for %s = %q, want root/team-a", path, namespace)
+		}
+	}
+	if len(namespaces) != 2 {
+		t.Errorf("expected a preflight and a read request, got %v", namespaces)
+	}
+
+	if got := client.client.Namespace(); got != "root" {
+		t.Errorf("namespace of the shared client = %q, want root", got)
+	}
+
+	client.restrictNamespace = true
+	if _, err := client.GetSecret(context.Background(), "kv/team", nil, 0, false, "team-a"); err != nil {
+		t.Fatalf("GetSecret returned an error: %v", err)
+	}
+	if got := namespaces["/v1/kv/team"]; got != "root" {
+		t.Errorf("namespace for a restricted client = %q, want root", got)
+	}
+}
+
+// newTestNamespaceClient returns a client with the root namespace "root" for a
+// Vault server, which responds with the given body to all requests. The
+// returned function returns the namespaces of all received requests.
+func newTestNamespaceClient(t *testing.T, body string) (*Client, func() []string) {
+	t.Helper()
+
+	var (
+		mu         sync.Mutex
+		namespaces []string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		namespaces = append(namespaces, r.Header.Get("X-Vault-Namespace"))
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	client := newTestClient(t, srv.URL)
+	client.client.SetNamespace("root")
+	client.rootVaultNamespace = "root"
+
+	return client, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(namespaces)
+	}
+}
+
+// assertNamespaces fails the test, when not all requests were sent for the
+// "team-a" namespace.
+func assertNamespaces(t *testing.T, namespaces []string) {
+	t.Helper()
+
+	if len(namespaces) == 0 {
+		t.Fatal("expected at least one request")
+	}
+	for _, namespace := range namespaces {
+		if namespace != "root/team-a" {
+			t.Errorf("namespace = %q, want root/team-a", namespace)
+		}
+	}
+}
+
+// TestGetSecretNamespaceWithoutRootNamespace verifies that the vaultNamespace
+// field can not be used, when the VAULT_NAMESPACE environment variable is not
+// set.
+func TestGetSecretNamespaceWithoutRootNamespace(t *testing.T) {
+	client := newTestClient(t, "http://127.0.0.1:0")
+
+	if _, err := client.GetSecret(context.Background(), "kv/team", nil, 0, false, "team-a"); err == nil {
+		t.Error("expected an error when vaultNamespace is set without a root namespace")
+	}
+}
+
+// TestSetToken verifies that the token, the lease duration and the renewal
+// interval are updated after a login and that the client can be used by
+// multiple goroutines in parallel.
+func TestSetToken(t *testing.T) {
+	client := newTestClient(t, "http://127.0.0.1:0")
+
+	if err := client.setToken(nil, ""); err == nil {
+		t.Error("expected an error for missing authentication information")
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			_ = client.setToken(&api.SecretAuth{ClientToken: "token", LeaseDuration: 3600}, "")
+			client.renewTokenFailed()
+			_ = client.GetHealth(time.Hour)
+			client.renewTokenSucceeded()
+		})
+	}
+	wg.Wait()
+
+	if got := client.client.Token(); got != "token" {
+		t.Errorf("token = %q, want token", got)
+	}
+	if got := client.getTokenLeaseDuration(); got != 3600 {
+		t.Errorf("token lease duration = %d, want 3600", got)
+	}
+	if got := client.getTokenRenewalInterval(); got != 30*time.Minute {
+		t.Errorf("token renewal interval = %s, want 30m", got)
+	}
+
+	if err := client.setToken(&api.SecretAuth{ClientToken: "token", LeaseDuration: 3600}, "60"); err != nil {
+		t.Fatalf("setToken returned an error: %v", err)
+	}
+	if got := client.getTokenRenewalInterval(); got != time.Minute {
+		t.Errorf("token renewal interval = %s, want 1m", got)
+	}
+}
+
+// TestCreateClientWithServiceAccountToken verifies that the given service
+// account token is used for the Kubernetes auth method instead of the token of
+// the operator.
+func TestCreateClientWithServiceAccountToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/kubernetes/login" {
+			http.NotFound(w, r)
+			return
+		}
+
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if body["jwt"] != "team-a-token" || body["role"] != "team-a" {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"auth": {"client_token": "team-a-vault-token", "lease_duration": 3600, "renewable": true}}`))
+	}))
+	defer srv.Close()
+
+	t.Setenv("VAULT_ADDRESS", srv.URL)
+	t.Setenv("VAULT_AUTH_METHOD", "kubernetes")
+	t.Setenv("VAULT_KUBERNETES_PATH", "auth/kubernetes")
+	t.Setenv("VAULT_TOKEN_PATH", filepath.Join(t.TempDir(), "missing"))
+
+	client, err := CreateClientWithServiceAccountToken("team-a", func() (string, error) {
+		return "team-a-token", nil
+	})
+	if err != nil {
+		t.Fatalf("CreateClientWithServiceAccountToken returned an error: %v", err)
+	}
+	if token := client.client.Token(); token != "team-a-vault-token" {
+		t.Errorf("token = %q, want team-a-vault-token", token)
+	}
+
+	if _, err := CreateClient("team-a"); err == nil {
+		t.Error("expected an error, when the token of the operator is missing")
+	}
+}
+
+// TestIsPermanentError verifies that only client errors returned by Vault are
+// permanent errors.
+func TestIsPermanentError(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{err: nil, want: false},
+		{err: &api.ResponseError{StatusCode: http.StatusForbidden}, want: false},
+		{err: &api.ResponseError{StatusCode: http.StatusNotFound}, want: true},
+		{err: fmt.Errorf("revoke: %w", &api.ResponseError{StatusCode: http.StatusBadRequest}), want: true},
+		{err: &api.ResponseError{StatusCode: http.StatusTooManyRequests}, want: false},
+		{err: &api.ResponseError{StatusCode: http.StatusServiceUnavailable}, want: false},
+		{err: errors.New("connection refused"), want: false},
+	} {
+		if got := IsPermanentError(tc.err); got != tc.want {
+			t.Errorf("IsPermanentError(%v) = %t, want %t", tc.err, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ricoberger/vault-secrets-operator/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// GetDatabaseCredentials returns dynamic credentials for the given role of the
+// database secrets engine mounted under the given path. Next to the
+// credentials the lease of the credentials is returned, so that the caller can
+// request new credentials before the lease expires.
+func (c *Client) GetDatabaseCredentials(ctx context.Context, path string, role string, vaultNamespace string) (_ map[string][]byte, _ *Lease, err error) {
+	log.Info(fmt.Sprintf("Read database credentials %s/creds/%s", path, role))
+
+	ctx, span := tracer.Start(ctx, "GetDatabaseCredentials", trace.WithAttributes(attribute.String("vault.path", path), attribute.String("vault.role", role)))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	client, err := c.namespacedClient(vaultNamespace)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	start := time.Now()
+	r, err := client.Logical().ReadWithContext(ctx, path+"/creds/"+role)
+	observeRequest(requestOperationDatabaseCreds, path, start, err)
+	setRequestID(ctx, r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if r == nil {
+		return nil, nil, fmt.Errorf("credentials are nil")
+	}
+
+	data, err := convertData(r.Data, nil, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, nil, fmt.Errorf("invalid credentials data")
+	}
+
+	lease := newLease(r)
+	c.leaseObtained(lease)
+	return data, lease, nil
+}
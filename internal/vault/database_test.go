@@ -0,0 +1,88 @@
+package vault
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestGetDatabaseCredentials verifies that the dynamic credentials and the lease
+// returned by Vault's database creds endpoint are exposed.
+func TestGetDatabaseCredentials(t *testing.T) {
+	var gotPath string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"lease_id": "database/creds/readonly/2f6a614c",
+			"lease_duration": 3600,
+			"renewable": true,
+			"data": {
+				"username": "v-token-readonly-9rqvz3",
+				"password": "A1a-8sbm2u9j5vv3w4ns"
+			}
+		}`))
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv.URL)
+
+	data, lease, err := client.GetDatabaseCredentials(context.Background(), "database", "readonly", "")
+	if err != nil {
+		t.Fatalf("GetDatabaseCredentials returned an error: %v", err)
+	}
+
+	if gotPath != "/v1/database/creds/readonly" {
+		t.Errorf("path = %q, want /v1/database/creds/readonly", gotPath)
+	}
+	if got := string(data["username"]); got != "v-token-readonly-9rqvz3" {
+		t.Errorf("username = %q, want v-token-readonly-9rqvz3", got)
+	}
+	if got := string(data["password"]); got != "A1a-8sbm2u9j5vv3w4ns" {
+		t.Errorf("password = %q, want A1a-8sbm2u9j5vv3w4ns", got)
+	}
+
+	if lease == nil {
+		t.Fatal("expected a lease, got nil")
+	}
+	if lease.ID != "database/creds/readonly/2f6a614c" {
+		t.Errorf("lease ID = %q, want database/creds/readonly/2f6a614c", lease.ID)
+	}
+	if lease.Duration != time.Hour {
+		t.Errorf("lease duration = %s, want 1h", lease.Duration)
+	}
+	if !lease.Renewable {
+		t.Error("expected the lease to be renewable")
+	}
+}
+
+// TestGetDatabaseCredentialsEmpty verifies that an empty response results in an
+// error.
+func TestGetDatabaseCredentialsEmpty(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {}}`))
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv.URL)
+
+	if _, _, err := client.GetDatabaseCredentials(context.Background(), "database", "readonly", ""); err == nil {
+		t.Fatal("expected an error for empty credentials, got nil")
+	}
+}
+
+// TestGetDatabaseCredentialsNamespace verifies that the namespace of a secret
+// is used to request the credentials.
+func TestGetDatabaseCredentialsNamespace(t *testing.T) {
+	client, namespaces := newTestNamespaceClient(t, `{"lease_id": "database/creds/readonly/1234", "lease_duration": 3600, "data": {"username": "v-token", "password": "secret"}}`)
+
+	if _, _, err := client.GetDatabaseCredentials(context.Background(), "database", "readonly", "team-a"); err != nil {
+		t.Fatalf("GetDatabaseCredentials returned an error: %v", err)
+	}
+
+	assertNamespaces(t, namespaces())
+}
@@ -0,0 +1,86 @@
+package vault
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestCreateClientJWTAuth verifies that the operator authenticates with the
+// projected service account token when the jwt auth method is used and that the
+// token file is read again, when a new Vault token is requested.
+func TestCreateClientJWTAuth(t *testing.T) {
+	var jwts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/oidc/login" {
+			http.NotFound(w, r)
+			return
+		}
+
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if body["role"] != "web" {
+			http.Error(w, "unexpected role", http.StatusBadRequest)
+			return
+		}
+		jwt, _ := body["jwt"].(string)
+		jwts = append(jwts, jwt)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"auth": {"client_token": "token-` + jwt + `", "lease_duration": 3600, "renewable": true}}`))
+	}))
+	defer srv.Close()
+
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenPath, []byte("first\n"), 0o600); err != nil {
+		t.Fatalf("failed to write token: %v", err)
+	}
+
+	t.Setenv("VAULT_ADDRESS", srv.URL)
+	t.Setenv("VAULT_AUTH_METHOD", "jwt")
+	t.Setenv("VAULT_JWT_PATH", "auth/oidc")
+	t.Setenv("VAULT_TOKEN_PATH", tokenPath)
+
+	client, err := CreateClient("web")
+	if err != nil {
+		t.Fatalf("CreateClient returned an error: %v", err)
+	}
+	if token := client.client.Token(); token != "token-first" {
+		t.Errorf("token = %q, want token-first", token)
+	}
+
+	if err := os.WriteFile(tokenPath, []byte("second"), 0o600); err != nil {
+		t.Fatalf("failed to write token: %v", err)
+	}
+	if err := client.requestToken(client); err != nil {
+		t.Fatalf("requestToken returned an error: %v", err)
+	}
+	if token := client.client.Token(); token != "token-second" {
+		t.Errorf("token = %q, want token-second", token)
+	}
+
+	if len(jwts) != 2 || jwts[0] != "first" || jwts[1] != "second" {
+		t.Errorf("jwts = %v, want [first second]", jwts)
+	}
+}
+
+// TestCreateClientJWTAuthWithoutRole verifies that no shared client is created
+// for the jwt auth method, when no role is set.
+func TestCreateClientJWTAuthWithoutRole(t *testing.T) {
+	t.Setenv("VAULT_ADDRESS", "http://127.0.0.1:8200")
+	t.Setenv("VAULT_AUTH_METHOD", "jwt")
+
+	client, err := CreateClient("")
+	if err != nil {
+		t.Fatalf("CreateClient returned an error: %v", err)
+	}
+	if client != nil {
+		t.Error("expected no client without a role")
+	}
+}
@@ -0,0 +1,159 @@
+package vault
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// kvMountCacheKey is the key for the cached mount information of a KV secrets
+// engine. The mount path always ends with a slash.
+type kvMountCacheKey struct {
+	namespace string
+	mountPath string
+}
+
+// kvMountCacheEntry is the cached version of a KV secrets engine.
+type kvMountCacheEntry struct {
+	version int
+	expires time.Time
+}
+
+// kvMountCache caches the mount path and the version of the KV secrets engines,
+// so that the preflight request against the "sys/internal/ui/mounts" endpoint
+// is not required before every read of a secret. Entries are cached per Vault
+// namespace and mount path for the configured TTL. Additionally a static
+// version can be configured per mount path, which is always used and never
+// expires, e.g. when the policy of the operator denies the preflight request.
+// A nil cache is valid and caches nothing.
+type kvMountCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	static  map[string]int
+	entries map[kvMountCacheKey]kvMountCacheEntry
+}
+
+// newKVMountCache returns a new cache for the mount information of the KV
+// secrets engines. A TTL of zero disables the caching of the preflight
+// requests, but the static versions are still used.
+func newKVMountCache(ttl time.Duration, static map[string]int) *kvMountCache {
+	return &kvMountCache{
+		ttl:     ttl,
+		static:  static,
+		entries: make(map[kvMountCacheKey]kvMountCacheEntry),
+	}
+}
+
+// parseKVVersions parses the static KV versions from a comma separated list of
+// mount paths and versions, e.g. "kv=2,secret=1".
+func parseKVVersions(value string) (map[string]int, error) {
+	versions := make(map[string]int)
+	for item := range strings.SplitSeq(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		mountPath, versionStr, ok := strings.Cut(item, "=")
+		mountPath = strings.Trim(strings.TrimSpace(mountPath), "/")
+		if !ok || mountPath == "" {
+			return nil, fmt.Errorf("invalid kv version %q, expected <mount>=<version>", item)
+		}
+
+		version, err := strconv.Atoi(strings.TrimSpace(versionStr))
+		if err != nil || (version != 1 && version != 2) {
+			return nil, fmt.Errorf("invalid kv version %q, version must be 1 or 2", item)
+		}
+
+		versions[mountPath+"/"] = version
+	}
+
+	return versions, nil
+}
+
+// get returns the mount path and the version of the KV secrets engine for the
+// given path in the given namespace. If multiple mount paths match the given
+// path, the longest mount path is used. The returned boolean is false when the
+// mount information is not cached.
+func (m *kvMountCache) get(namespace, path string) (string, int, bool) {
+	if m == nil {
+		return "", 0, false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var (
+		mountPath string
+		version   int
+	)
+
+	for staticMountPath, staticVersion := range m.static {
+		if matchesMountPath(path, staticMountPath) && len(staticMountPath) > len(mountPath) {
+			mountPath, version = staticMountPath, staticVersion
+		}
+	}
+	if mountPath != "" {
+		return mountPath, version, true
+	}
+
+	now := time.Now()
+	for key, entry := range m.entries {
+		if key.namespace != namespace || !matchesMountPath(path, key.mountPath) {
+			continue
+		}
+		if now.After(entry.expires) {
+			delete(m.entries, key)
+			continue
+		}
+		if len(key.mountPath) > len(mountPath) {
+			mountPath, version = key.mountPath, entry.version
+		}
+	}
+
+	return mountPath, version, mountPath != ""
+}
+
+// set caches the version of the KV secrets engine mounted under the given mount
+// path in the given namespace.
+func (m *kvMountCache) set(namespace, mountPath string, version int) {
+	if m == nil || m.ttl <= 0 || mountPath == "" {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries[kvMountCacheKey{namespace: namespace, mountPath: normalizeMountPath(mountPath)}] = kvMountCacheEntry{
+		version: version,
+		expires: time.Now().Add(m.ttl),
+	}
+}
+
+// invalidate removes the cached version of the KV secrets engine mounted under
+// the given mount path in the given namespace. The static versions are never
+// removed.
+func (m *kvMountCache) invalidate(namespace, mountPath string) {
+	if m == nil || mountPath == "" {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, kvMountCacheKey{namespace: namespace, mountPath: normalizeMountPath(mountPath)})
+}
+
+// matchesMountPath returns true if the given path is the mount path or a path
+// below the mount path.
+func matchesMountPath(path, mountPath string) bool {
+	return path == strings.TrimSuffix(mountPath, "/") || strings.HasPrefix(path, mountPath)
+}
+
+// normalizeMountPath returns the mount path without a leading slash and with a
+// trailing slash.
+func normalizeMountPath(mountPath string) string {
+	return strings.TrimPrefix(strings.TrimSuffix(mountPath, "/"), "/") + "/"
+}
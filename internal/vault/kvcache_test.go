@@ -0,0 +1,189 @@
+package vault
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseKVVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]int
+		wantErr bool
+	}{
+		{name: "empty", value: "", want: map[string]int{}},
+		{name: "multiple mounts", value: "kv=2, secret/=1,/team/kv=2", want: map[string]int{"kv/": 2, "secret/": 1, "team/kv/": 2}},
+		{name: "missing version", value: "kv", wantErr: true},
+		{name: "invalid version", value: "kv=3", wantErr: true},
+		{name: "missing mount", value: "=2", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseKVVersions(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseKVVersions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseKVVersions() = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("parseKVVersions()[%q] = %d, want %d", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+// TestKVMountCache verifies that the cached mount information is returned per
+// namespace for the longest matching mount path until it expires or is
+// invalidated, and that static versions take precedence.
+func TestKVMountCache(t *testing.T) {
+	cache := newKVMountCache(time.Minute, map[string]int{"static/": 1})
+
+	cache.set("", "kv/", 2)
+	cache.set("", "kv/team/", 1)
+	cache.set("team-a", "kv/", 1)
+
+	if mountPath, version, ok := cache.get("", "kv/app"); !ok || mountPath != "kv/" || version != 2 {
+		t.Errorf("get(kv/app) = %q, %d, %t", mountPath, version, ok)
+	}
+	if mountPath, version, ok := cache.get("", "kv/team/app"); !ok || mountPath != "kv/team/" || version != 1 {
+		t.Errorf("get(kv/team/app) = %q, %d, %t", mountPath, version, ok)
+	}
+	if _, version, ok := cache.get("team-a", "kv/app"); !ok || version != 1 {
+		t.Errorf("get(kv/app) in namespace team-a = %d, %t", version, ok)
+	}
+	if _, _, ok := cache.get("", "kvv1/app"); ok {
+		t.Error("expected no cached mount for kvv1/app")
+	}
+	if mountPath, version, ok := cache.get("team-b", "static/app"); !ok || mountPath != "static/" || version != 1 {
+		t.Errorf("get(static/app) = %q, %d, %t", mountPath, version, ok)
+	}
+
+	cache.invalidate("", "kv/")
+	if mountPath, _, ok := cache.get("", "kv/app"); ok {
+		t.Errorf("expected kv/ to be invalidated, got %q", mountPath)
+	}
+
+	cache.invalidate("", "static/")
+	if _, _, ok := cache.get("", "static/app"); !ok {
+		t.Error("static versions must not be invalidated")
+	}
+
+	expired := newKVMountCache(time.Nanosecond, nil)
+	expired.set("", "kv/", 2)
+	time.Sleep(time.Millisecond)
+	if _, _, ok := expired.get("", "kv/app"); ok {
+		t.Error("expected the cached mount to be expired")
+	}
+
+	disabled := newKVMountCache(0, nil)
+	disabled.set("", "kv/", 2)
+	if _, _, ok := disabled.get("", "kv/app"); ok {
+		t.Error("expected no caching with a TTL of zero")
+	}
+}
+
+// TestGetSecretKVMountCache verifies that the preflight request is only sent
+// once for multiple reads and again after a read, which indicates a wrong
+// mount, but not after other failed reads.
+func TestGetSecretKVMountCache(t *testing.T) {
+	var (
+		preflights atomic.Int32
+		fail       atomic.Int32
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasPrefix(r.URL.Path, "/v1/sys/internal/ui/mounts/") {
+			preflights.Add(1)
+			_, _ = w.Write([]byte(`{"data": {"path": "kv/", "options": {"version": "2"}}}`))
+			return
+		}
+		switch fail.Load() {
+		case http.StatusOK:
+			_, _ = w.Write([]byte(`{"data": null, "warnings": ["Invalid path for a versioned K/V secrets engine. See the API docs for the appropriate API endpoints to use."]}`))
+			return
+		case http.StatusForbidden, http.StatusNotFound:
+			w.WriteHeader(int(fail.Load()))
+			_, _ = w.Write([]byte(`{"errors": []}`))
+			return
+		}
+		if r.URL.Path != "/v1/kv/data/app" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"data": {"data": {"username": "admin"}}}`))
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv.URL)
+	client.kvMounts = newKVMountCache(time.Minute, nil)
+
+	for range 3 {
+		if _, err := client.GetSecret(context.Background(), "kv/app", nil, 0, false, ""); err != nil {
+			t.Fatalf("GetSecret returned an error: %v", err)
+		}
+	}
+	if got := preflights.Load(); got != 1 {
+		t.Errorf("preflight requests = %d, want 1", got)
+	}
+
+	for _, tc := range []struct {
+		code       int32
+		preflights int32
+	}{
+		{code: http.StatusForbidden, preflights: 1},
+		{code: http.StatusNotFound, preflights: 2},
+		{code: http.StatusOK, preflights: 3},
+	} {
+		fail.Store(tc.code)
+		if _, err := client.GetSecret(context.Background(), "kv/app", nil, 0, false, ""); err == nil {
+			t.Fatalf("expected an error for a failed read with status %d", tc.code)
+		}
+		fail.Store(0)
+
+		if _, err := client.GetSecret(context.Background(), "kv/app", nil, 0, false, ""); err != nil {
+			t.Fatalf("GetSecret returned an error: %v", err)
+		}
+		if got := preflights.Load(); got != tc.preflights {
+			t.Errorf("preflight requests after a failed read with status %d = %d, want %d", tc.code, got, tc.preflights)
+		}
+	}
+}
+
+// TestGetSecretKVVersionOverride verifies that no preflight request is sent,
+// when the version of the KV secrets engine is configured statically.
+func TestGetSecretKVVersionOverride(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/v1/sys/internal/ui/mounts/") {
+			t.Error("unexpected preflight request")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"data": {"username": "admin"}}}`))
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv.URL)
+	client.kvMounts = newKVMountCache(time.Minute, map[string]int{"kv/": 2})
+
+	data, err := client.GetSecret(context.Background(), "kv/app", nil, 0, false, "")
+	if err != nil {
+		t.Fatalf("GetSecret returned an error: %v", err)
+	}
+	if string(data["username"]) != "admin" {
+		t.Errorf("username = %q, want admin", data["username"])
+	}
+}
@@ -0,0 +1,127 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ricoberger/vault-secrets-operator/internal/tracing"
+
+	"github.com/hashicorp/vault/api"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Lease is the lease of a dynamic secret, which was returned by Vault.
+type Lease struct {
+	// ID is the lease ID, which can be used to renew or revoke the lease.
+	ID string
+	// Duration is the time the secret is valid for.
+	Duration time.Duration
+	// Renewable indicates if the lease can be renewed.
+	Renewable bool
+}
+
+// newLease returns the lease of the given secret. If the secret does not
+// contain a lease, nil is returned.
+func newLease(secret *api.Secret) *Lease {
+	if secret.LeaseID == "" && secret.LeaseDuration == 0 {
+		return nil
+	}
+
+	return &Lease{
+		ID:        secret.LeaseID,
+		Duration:  time.Duration(secret.LeaseDuration) * time.Second,
+		Renewable: secret.Renewable,
+	}
+}
+
+// leaseObtained remembers the expiration time of the given lease, which was
+// created or renewed with the token of the client. The lease can be nil, if the
+// secret doesn't contain a lease.
+func (c *Client) leaseObtained(lease *Lease) {
+	if lease == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if expireTime := time.Now().Add(lease.Duration); expireTime.After(c.leaseExpireTime) {
+		c.leaseExpireTime = expireTime
+	}
+}
+
+// hasValidLeases returns true, when a lease which was created or renewed with
+// the client is still valid at the given time. The token of such a client must
+// not be revoked, because Vault would also revoke the leases.
+func (c *Client) hasValidLeases(now time.Time) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return now.Before(c.leaseExpireTime)
+}
+
+// GetLeaseRenew returns the minimum remaining period of validity before a
+// dynamic secret is renewed.
+func (c *Client) GetLeaseRenew() time.Duration {
+	return c.leaseRenew
+}
+
+// RevokeLease revokes the lease with the given ID, so that the corresponding
+// dynamic secret can not be used anymore. When Vault denies the revocation, it
+// is retried once with a new token.
+func (c *Client) RevokeLease(ctx context.Context, leaseID string, vaultNamespace string) (err error) {
+	log.Info(fmt.Sprintf("Revoke lease %s", leaseID))
+
+	ctx, span := tracer.Start(ctx, "RevokeLease", trace.WithAttributes(attribute.String("vault.lease_id", leaseID)))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	return c.withRelogin(func() error {
+		client, err := c.namespacedClient(vaultNamespace)
+		if err != nil {
+			return err
+		}
+
+		start := time.Now()
+		err = client.Sys().RevokeWithContext(ctx, leaseID)
+		observeRequest(requestOperationLeaseRevoke, "sys/leases", start, err)
+		return err
+	})
+}
+
+// RenewLease renews the lease with the given ID. The increment is the requested
+// duration of the lease, which is capped by Vault at the maximum TTL of the
+// lease. The returned lease contains the new duration of the lease, so that the
+// caller can detect when the maximum TTL is reached.
+func (c *Client) RenewLease(ctx context.Context, leaseID string, increment time.Duration, vaultNamespace string) (_ *Lease, err error) {
+	log.Info(fmt.Sprintf("Renew lease %s", leaseID))
+
+	ctx, span := tracer.Start(ctx, "RenewLease", trace.WithAttributes(attribute.String("vault.lease_id", leaseID)))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	client, err := c.namespacedClient(vaultNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	secret, err := client.Sys().RenewWithContext(ctx, leaseID, int(increment.Seconds()))
+	observeRequest(requestOperationLeaseRenew, "sys/leases", start, err)
+	setRequestID(ctx, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if secret == nil {
+		return nil, fmt.Errorf("lease is nil")
+	}
+
+	lease := newLease(secret)
+	if lease == nil {
+		return nil, fmt.Errorf("missing lease information")
+	}
+
+	c.leaseObtained(lease)
+	return lease, nil
+}
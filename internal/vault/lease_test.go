@@ -0,0 +1,149 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestRevokeLease verifies that the lease ID is sent to Vault's lease revoke
+// endpoint.
+func TestRevokeLease(t *testing.T) {
+	var (
+		gotMethod string
+		gotPath   string
+		gotBody   map[string]any
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotPath = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv.URL)
+
+	if err := client.RevokeLease(context.Background(), "database/creds/readonly/2f6a614c", ""); err != nil {
+		t.Fatalf("RevokeLease returned an error: %v", err)
+	}
+
+	if gotMethod != http.MethodPut {
+		t.Errorf("method = %q, want PUT", gotMethod)
+	}
+	if gotPath != "/v1/sys/leases/revoke" {
+		t.Errorf("path = %q, want /v1/sys/leases/revoke", gotPath)
+	}
+	if got := gotBody["lease_id"]; got != "database/creds/readonly/2f6a614c" {
+		t.Errorf("lease_id = %v, want database/creds/readonly/2f6a614c", got)
+	}
+}
+
+// TestRevokeLeaseRelogin verifies that a revocation, which is denied by Vault,
+// is retried once with a new token and that the error is returned, when it is
+// still denied.
+func TestRevokeLeaseRelogin(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		denied  int
+		wantErr bool
+	}{
+		{name: "token expired", denied: 1},
+		{name: "permission denied", denied: 2, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var logins, revocations int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/v1/auth/approle/login":
+					logins++
+					_, _ = w.Write([]byte(`{"auth": {"client_token": "new", "lease_duration": 3600, "renewable": true}}`))
+				case "/v1/sys/leases/revoke":
+					revocations++
+					if revocations <= tc.denied {
+						http.Error(w, `{"errors": ["permission denied"]}`, http.StatusForbidden)
+						return
+					}
+					if token := r.Header.Get("X-Vault-Token"); token != "new" {
+						t.Errorf("token = %q, want new", token)
+					}
+					w.WriteHeader(http.StatusNoContent)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer srv.Close()
+
+			client := newTestRenewerClient(t, srv.URL, true)
+			if err := client.RevokeLease(context.Background(), "database/creds/readonly/2f6a614c", ""); (err != nil) != tc.wantErr {
+				t.Fatalf("RevokeLease returned error %v, want error %t", err, tc.wantErr)
+			}
+			if logins != 1 || revocations != 2 {
+				t.Errorf("logins = %d, revocations = %d, want 1 and 2", logins, revocations)
+			}
+		})
+	}
+}
+
+// TestRenewLease verifies that the lease is renewed with the requested
+// increment and that the new lease duration is returned.
+func TestRenewLease(t *testing.T) {
+	var (
+		gotPath string
+		gotBody map[string]any
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"lease_id": "database/creds/readonly/2f6a614c",
+			"lease_duration": 1200,
+			"renewable": true
+		}`))
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv.URL)
+
+	lease, err := client.RenewLease(context.Background(), "database/creds/readonly/2f6a614c", time.Hour, "")
+	if err != nil {
+		t.Fatalf("RenewLease returned an error: %v", err)
+	}
+
+	if gotPath != "/v1/sys/leases/renew" {
+		t.Errorf("path = %q, want /v1/sys/leases/renew", gotPath)
+	}
+	if got := gotBody["lease_id"]; got != "database/creds/readonly/2f6a614c" {
+		t.Errorf("lease_id = %v, want database/creds/readonly/2f6a614c", got)
+	}
+	if got := gotBody["increment"]; got != float64(3600) {
+		t.Errorf("increment = %v, want 3600", got)
+	}
+
+	// The lease duration is capped by the maximum TTL, so the returned
+	// duration can be shorter than the requested increment.
+	if lease.Duration != 20*time.Minute {
+		t.Errorf("lease duration = %s, want 20m", lease.Duration)
+	}
+}
+
+// TestLeaseNamespace verifies that the namespace of a secret is used to renew
+// and revoke the lease.
+func TestLeaseNamespace(t *testing.T) {
+	client, namespaces := newTestNamespaceClient(t, `{"lease_id": "database/creds/readonly/1234", "lease_duration": 3600, "renewable": true}`)
+
+	if _, err := client.RenewLease(context.Background(), "database/creds/readonly/1234", time.Hour, "team-a"); err != nil {
+		t.Fatalf("RenewLease returned an error: %v", err)
+	}
+	if err := client.RevokeLease(context.Background(), "database/creds/readonly/1234", "team-a"); err != nil {
+		t.Fatalf("RevokeLease returned an error: %v", err)
+	}
+
+	assertNamespaces(t, namespaces())
+}
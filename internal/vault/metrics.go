@@ -0,0 +1,149 @@
+package vault
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	loginStatusSuccess = "success"
+	loginStatusFailure = "failure"
+)
+
+// Operations of the requests against Vault, which are used as operation label
+// for the request metrics.
+const (
+	requestOperationLogin          = "login"
+	requestOperationRenew          = "renew"
+	requestOperationRevoke         = "revoke"
+	requestOperationPreflight      = "preflight"
+	requestOperationKVRead         = "kv_read"
+	requestOperationPKIIssue       = "pki_issue"
+	requestOperationPKISign        = "pki_sign"
+	requestOperationPKIRevoke      = "pki_revoke"
+	requestOperationDatabaseCreds  = "database_creds"
+	requestOperationAWSCreds       = "aws_creds"
+	requestOperationSSHSign        = "ssh_sign"
+	requestOperationTransitDecrypt = "transit_decrypt"
+	requestOperationLeaseRenew     = "lease_renew"
+	requestOperationLeaseRevoke    = "lease_revoke"
+)
+
+const (
+	// requestStatusSuccess is the status label for successful requests, because
+	// the Vault API client does not return the status code of successful
+	// responses.
+	requestStatusSuccess = "2xx"
+	// requestStatusError is the status label for requests which failed without
+	// a response from Vault, e.g. because of a connection error.
+	requestStatusError = "error"
+)
+
+var (
+	tokenTTLSeconds = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "vaultsecrets_token_ttl_seconds",
+			Help: "Remaining TTL of the Vault token of the shared client in seconds",
+		},
+		func() float64 {
+			expireTime, _, _ := sharedClientTokenStatus()
+			if expireTime.IsZero() {
+				return 0
+			}
+			return max(time.Until(expireTime).Seconds(), 0)
+		},
+	)
+	tokenLastRenewalTimestampSeconds = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "vaultsecrets_token_last_renewal_timestamp_seconds",
+			Help: "Unix timestamp of the last successful login or renewal of the Vault token of the shared client",
+		},
+		func() float64 {
+			_, renewTime, _ := sharedClientTokenStatus()
+			if renewTime.IsZero() {
+				return 0
+			}
+			return float64(renewTime.Unix())
+		},
+	)
+	tokenRenewalFailures = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "vaultsecrets_token_renewal_failures",
+			Help: "Number of consecutive failed renewals of the Vault token of the shared client",
+		},
+		func() float64 {
+			_, _, failures := sharedClientTokenStatus()
+			return float64(failures)
+		},
+	)
+	tokenLoginsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vaultsecrets_token_logins_total",
+			Help: "Total number of logins to request a new Vault token after the initial login",
+		},
+		[]string{"auth_method", "status"},
+	)
+	requestDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "vaultsecrets_vault_request_duration_seconds",
+			Help:    "Duration of requests against Vault in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"operation", "mount"},
+	)
+	requestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vaultsecrets_vault_requests_total",
+			Help: "Total number of requests against Vault",
+		},
+		[]string{"operation", "mount", "status"},
+	)
+)
+
+func init() {
+	// Register custom metrics with the global prometheus registry
+	metrics.Registry.MustRegister(tokenTTLSeconds)
+	metrics.Registry.MustRegister(tokenLastRenewalTimestampSeconds)
+	metrics.Registry.MustRegister(tokenRenewalFailures)
+	metrics.Registry.MustRegister(tokenLoginsTotal)
+	metrics.Registry.MustRegister(requestDurationSeconds)
+	metrics.Registry.MustRegister(requestsTotal)
+}
+
+// sharedClientTokenStatus returns the status of the token of the shared client.
+// If the shared client is not initialized, zero values are returned.
+func sharedClientTokenStatus() (time.Time, time.Time, int) {
+	if SharedClient == nil {
+		return time.Time{}, time.Time{}, 0
+	}
+	return SharedClient.tokenStatus()
+}
+
+// observeRequest records the duration and the status of a request against
+// Vault, which was started at the given time. The mount is the path of the
+// auth method or secrets engine, which was used for the request.
+func observeRequest(operation, mount string, start time.Time, err error) {
+	requestDurationSeconds.WithLabelValues(operation, mount).Observe(time.Since(start).Seconds())
+	requestsTotal.WithLabelValues(operation, mount, requestStatus(err)).Inc()
+}
+
+// requestStatus returns the status label for a request against Vault, which
+// returned the given error. For errors returned by Vault the HTTP status code
+// of the response is used.
+func requestStatus(err error) string {
+	if err == nil {
+		return requestStatusSuccess
+	}
+
+	var respErr *api.ResponseError
+	if errors.As(err, &respErr) && respErr.StatusCode > 0 {
+		return strconv.Itoa(respErr.StatusCode)
+	}
+
+	return requestStatusError
+}
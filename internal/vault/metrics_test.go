@@ -0,0 +1,108 @@
+package vault
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestTokenMetrics verifies that the metrics for the token of the shared client
+// are updated by the token renewal and that the logins are counted by auth
+// method.
+func TestTokenMetrics(t *testing.T) {
+	sharedClient := SharedClient
+	defer func() { SharedClient = sharedClient }()
+
+	SharedClient = nil
+	if ttl := testutil.ToFloat64(tokenTTLSeconds); ttl != 0 {
+		t.Errorf("ttl = %f, want 0 without a shared client", ttl)
+	}
+
+	server := &testRenewerServer{renewTTL: 600, renewable: true, failRenew: true}
+	srv := httptest.NewServer(http.HandlerFunc(server.handler))
+	defer srv.Close()
+
+	SharedClient = newTestRenewerClient(t, srv.URL, false)
+	SharedClient.authMethod = "approle"
+
+	if _, err := SharedClient.renewTokenOnce(newTokenRenewal(time.Now()), time.Now()); err == nil {
+		t.Fatal("expected an error")
+	}
+	if failures := testutil.ToFloat64(tokenRenewalFailures); failures != 1 {
+		t.Errorf("failures = %f, want 1", failures)
+	}
+
+	server.failRenew = false
+	if _, err := SharedClient.renewTokenOnce(newTokenRenewal(time.Now()), time.Now()); err != nil {
+		t.Fatalf("renewTokenOnce returned an error: %v", err)
+	}
+	if failures := testutil.ToFloat64(tokenRenewalFailures); failures != 0 {
+		t.Errorf("failures = %f, want 0", failures)
+	}
+	if ttl := testutil.ToFloat64(tokenTTLSeconds); ttl > 600 || ttl < 590 {
+		t.Errorf("ttl = %f, want about 600", ttl)
+	}
+	if renewed := testutil.ToFloat64(tokenLastRenewalTimestampSeconds); time.Since(time.Unix(int64(renewed), 0)) > time.Minute {
+		t.Errorf("last renewal = %f, want about now", renewed)
+	}
+
+	logins := testutil.ToFloat64(tokenLoginsTotal.WithLabelValues("approle", loginStatusSuccess))
+	SharedClient.requestToken = newTestRenewerClient(t, srv.URL, true).requestToken
+	if _, err := SharedClient.renewTokenOnce(&tokenRenewal{issued: time.Now()}, time.Now()); err != nil {
+		t.Fatalf("renewTokenOnce returned an error: %v", err)
+	}
+	if got := testutil.ToFloat64(tokenLoginsTotal.WithLabelValues("approle", loginStatusSuccess)); got != logins+1 {
+		t.Errorf("logins = %f, want %f", got, logins+1)
+	}
+}
+
+// TestRequestMetrics verifies that the requests against Vault are counted by
+// operation, mount and status and that their duration is recorded.
+func TestRequestMetrics(t *testing.T) {
+	server := &testRenewerServer{failRenew: true}
+	srv := httptest.NewServer(http.HandlerFunc(server.handler))
+	defer srv.Close()
+
+	c := newTestRenewerClient(t, srv.URL, false)
+
+	forbidden := testutil.ToFloat64(requestsTotal.WithLabelValues(requestOperationRenew, "auth/token", "403"))
+	if _, err := c.renewTokenOnce(newTokenRenewal(time.Now()), time.Now()); err == nil {
+		t.Fatal("expected an error")
+	}
+	if got := testutil.ToFloat64(requestsTotal.WithLabelValues(requestOperationRenew, "auth/token", "403")); got != forbidden+1 {
+		t.Errorf("requests = %f, want %f", got, forbidden+1)
+	}
+
+	success := testutil.ToFloat64(requestsTotal.WithLabelValues(requestOperationLogin, "auth/approle", requestStatusSuccess))
+	if _, err := login(c.client, "auth/approle", nil); err != nil {
+		t.Fatalf("login returned an error: %v", err)
+	}
+	if got := testutil.ToFloat64(requestsTotal.WithLabelValues(requestOperationLogin, "auth/approle", requestStatusSuccess)); got != success+1 {
+		t.Errorf("requests = %f, want %f", got, success+1)
+	}
+	if count := testutil.CollectAndCount(requestDurationSeconds); count == 0 {
+		t.Error("expected the request duration to be recorded")
+	}
+}
+
+// TestRequestStatus verifies the status label for successful requests, errors
+// returned by Vault and connection errors.
+func TestRequestStatus(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want string
+	}{
+		{err: nil, want: requestStatusSuccess},
+		{err: &api.ResponseError{StatusCode: http.StatusNotFound}, want: "404"},
+		{err: errors.New("connection refused"), want: requestStatusError},
+	} {
+		if got := requestStatus(tc.err); got != tc.want {
+			t.Errorf("requestStatus(%v) = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
@@ -1,23 +1,80 @@
 package vault
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/ricoberger/vault-secrets-operator/internal/tracing"
+
+	"github.com/hashicorp/vault/api"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func (c *Client) GetCertificate(path string, role string, options map[string]string) (map[string][]byte, *time.Time, error) {
+// GetCertificate issues a new certificate with the given role of the PKI
+// secrets engine mounted under the given path.
+func (c *Client) GetCertificate(ctx context.Context, path string, role string, options map[string]string, vaultNamespace string) (_ map[string][]byte, _ *time.Time, err error) {
+	ctx, span := tracer.Start(ctx, "GetCertificate", trace.WithAttributes(attribute.String("vault.path", path), attribute.String("vault.role", role)))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	client, err := c.namespacedClient(vaultNamespace)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	optionsI := make(map[string]any, len(options))
 	for k, v := range options {
 		optionsI[k] = v
 	}
 
-	r, err := c.client.Logical().Write(path+"/issue/"+role, optionsI)
+	start := time.Now()
+	r, err := client.Logical().WriteWithContext(ctx, path+"/issue/"+role, optionsI)
+	observeRequest(requestOperationPKIIssue, path, start, err)
+	setRequestID(ctx, r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return parseCertificate(r)
+}
+
+// SignCertificate signs the given PEM encoded certificate signing request with
+// the given role of the PKI secrets engine mounted under the given path. In
+// contrast to GetCertificate the private key never leaves the operator, so that
+// the returned data does not contain the "private_key" and "private_key_type"
+// fields.
+func (c *Client) SignCertificate(ctx context.Context, path string, role string, csr []byte, options map[string]string, vaultNamespace string) (_ map[string][]byte, _ *time.Time, err error) {
+	ctx, span := tracer.Start(ctx, "SignCertificate", trace.WithAttributes(attribute.String("vault.path", path), attribute.String("vault.role", role)))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	client, err := c.namespacedClient(vaultNamespace)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	optionsI := make(map[string]any, len(options)+1)
+	for k, v := range options {
+		optionsI[k] = v
+	}
+	optionsI["csr"] = string(csr)
+
+	start := time.Now()
+	r, err := client.Logical().WriteWithContext(ctx, path+"/sign/"+role, optionsI)
+	observeRequest(requestOperationPKISign, path, start, err)
+	setRequestID(ctx, r)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	return parseCertificate(r)
+}
+
+// parseCertificate returns the certificate data and the expiration date of the
+// certificate from the response of the PKI issue or sign endpoint.
+func parseCertificate(r *api.Secret) (map[string][]byte, *time.Time, error) {
 	if r == nil {
 		return nil, nil, fmt.Errorf("certificate is nil")
 	}
@@ -58,3 +115,28 @@ func (c *Client) GetCertificate(path string, role string, options map[string]str
 
 	return data, &expiration, nil
 }
+
+// RevokeCertificate revokes the certificate with the given serial number, which
+// was issued by the PKI secrets engine mounted under the given path. When Vault
+// denies the revocation, it is retried once with a new token.
+func (c *Client) RevokeCertificate(ctx context.Context, path string, serialNumber string, vaultNamespace string) (err error) {
+	log.Info(fmt.Sprintf("Revoke certificate %s", serialNumber))
+
+	ctx, span := tracer.Start(ctx, "RevokeCertificate", trace.WithAttributes(attribute.String("vault.path", path), attribute.String("vault.serial_number", serialNumber)))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	return c.withRelogin(func() error {
+		client, err := c.namespacedClient(vaultNamespace)
+		if err != nil {
+			return err
+		}
+
+		start := time.Now()
+		r, err := client.Logical().WriteWithContext(ctx, path+"/revoke", map[string]any{
+			"serial_number": serialNumber,
+		})
+		observeRequest(requestOperationPKIRevoke, path, start, err)
+		setRequestID(ctx, r)
+		return err
+	})
+}
@@ -1,6 +1,8 @@
 package vault
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -50,7 +52,7 @@ func TestGetCertificateCAChain(t *testing.T) {
 
 	client := newTestClient(t, srv.URL)
 
-	data, expiration, err := client.GetCertificate("pki", "example-dot-com", nil)
+	data, expiration, err := client.GetCertificate(context.Background(), "pki", "example-dot-com", nil, "")
 	if err != nil {
 		t.Fatalf("GetCertificate returned an error: %v", err)
 	}
@@ -69,6 +71,90 @@ func TestGetCertificateCAChain(t *testing.T) {
 	}
 }
 
+// TestRevokeCertificate verifies that the serial number is sent to the revoke
+// endpoint of the PKI secrets engine.
+func TestRevokeCertificate(t *testing.T) {
+	var (
+		gotPath string
+		gotBody map[string]any
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"revocation_time": 1649769202}}`))
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv.URL)
+
+	if err := client.RevokeCertificate(context.Background(), "pki", "00:11:22", ""); err != nil {
+		t.Fatalf("RevokeCertificate returned an error: %v", err)
+	}
+
+	if gotPath != "/v1/pki/revoke" {
+		t.Errorf("path = %q, want /v1/pki/revoke", gotPath)
+	}
+	if got := gotBody["serial_number"]; got != "00:11:22" {
+		t.Errorf("serial_number = %v, want 00:11:22", got)
+	}
+}
+
+// TestSignCertificate verifies that the CSR and the engine options are sent to
+// the sign endpoint of the PKI secrets engine and that the response is parsed
+// like the response of the issue endpoint.
+func TestSignCertificate(t *testing.T) {
+	const csr = "-----BEGIN CERTIFICATE REQUEST-----\ncsr\n-----END CERTIFICATE REQUEST-----\n"
+
+	var (
+		gotPath string
+		gotBody map[string]any
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"data": {
+				"certificate": "-----BEGIN CERTIFICATE-----\ncert\n-----END CERTIFICATE-----",
+				"expiration": 1649769202,
+				"issuing_ca": "-----BEGIN CERTIFICATE-----\nca\n-----END CERTIFICATE-----",
+				"serial_number": "00:11:22"
+			}
+		}`))
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv.URL)
+
+	data, expiration, err := client.SignCertificate(context.Background(), "pki", "example-dot-com", []byte(csr), map[string]string{"common_name": "www.example.com"}, "")
+	if err != nil {
+		t.Fatalf("SignCertificate returned an error: %v", err)
+	}
+
+	if gotPath != "/v1/pki/sign/example-dot-com" {
+		t.Errorf("path = %q, want /v1/pki/sign/example-dot-com", gotPath)
+	}
+	if got := gotBody["csr"]; got != csr {
+		t.Errorf("csr = %v, want %q", got, csr)
+	}
+	if got := gotBody["common_name"]; got != "www.example.com" {
+		t.Errorf("common_name = %v, want www.example.com", got)
+	}
+
+	if expiration == nil || expiration.Unix() != 1649769202 {
+		t.Errorf("unexpected expiration: %v", expiration)
+	}
+	if got := string(data["serial_number"]); got != "00:11:22" {
+		t.Errorf("serial_number = %q, want 00:11:22", got)
+	}
+	if _, ok := data["private_key"]; ok {
+		t.Error("private_key should not be returned by the sign endpoint")
+	}
+}
+
 // escape turns the newlines of a PEM string into the escaped form used inside a
 // JSON string literal.
 func escape(s string) string {
@@ -82,3 +168,21 @@ func escape(s string) string {
 	}
 	return string(out)
 }
+
+// TestPKINamespace verifies that the namespace of a secret is used to issue,
+// sign and revoke certificates.
+func TestPKINamespace(t *testing.T) {
+	client, namespaces := newTestNamespaceClient(t, `{"data": {"certificate": "cert", "expiration": 1700000000, "serial_number": "01:02"}}`)
+
+	if _, _, err := client.GetCertificate(context.Background(), "pki", "example", nil, "team-a"); err != nil {
+		t.Fatalf("GetCertificate returned an error: %v", err)
+	}
+	if _, _, err := client.SignCertificate(context.Background(), "pki", "example", []byte("csr"), nil, "team-a"); err != nil {
+		t.Fatalf("SignCertificate returned an error: %v", err)
+	}
+	if err := client.RevokeCertificate(context.Background(), "pki", "01:02", "team-a"); err != nil {
+		t.Fatalf("RevokeCertificate returned an error: %v", err)
+	}
+
+	assertNamespaces(t, namespaces())
+}
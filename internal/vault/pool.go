@@ -0,0 +1,220 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+)
+
+// clientPoolCheckInterval is the interval in which the clients of the pool are
+// checked for idle clients and tokens which must be renewed.
+var clientPoolCheckInterval = 30 * time.Second
+
+// clientPoolKey identifies a client of the pool. The namespace and the name of
+// the service account are only set, when the operator requests tokens for the
+// service accounts of the secrets, because the Vault token then depends on the
+// service account which was used for the login.
+type clientPoolKey struct {
+	vaultRole          string
+	namespace          string
+	serviceAccountName string
+}
+
+// clientPoolEntry is a client of the pool. The mutex of the entry must be held
+// while the client is created, renewed or evicted.
+type clientPoolEntry struct {
+	mu       sync.Mutex
+	client   *Client
+	evicted  bool
+	lastUsed time.Time
+	renewal  *tokenRenewal
+	renewAt  time.Time
+}
+
+// ClientPool caches the Vault clients for secrets with the vaultRole property,
+// so that the operator does not login to Vault and create a new token on every
+// reconciliation. The tokens of the cached clients are renewed in the
+// background. Clients which were not used for the idle timeout are evicted from
+// the pool and their tokens are revoked. Vault revokes all leases of a token
+// together with the token, so that clients which created leases (e.g. for the
+// database or AWS secrets engine) are kept in the pool until their leases
+// expired and their tokens are never revoked while the leases are valid. The
+// pool must be added to the manager as Runnable, which runs the renewal and the
+// eviction until the manager is stopped.
+type ClientPool struct {
+	mu          sync.Mutex
+	idleTimeout time.Duration
+	entries     map[clientPoolKey]*clientPoolEntry
+	// createClient creates the client for a new entry of the pool.
+	createClient func(vaultRole string, serviceAccountToken ServiceAccountToken) (*Client, error)
+}
+
+// NewClientPool returns a new pool for the Vault clients of secrets with the
+// vaultRole property. Clients which were not used for the given idle timeout
+// are evicted.
+func NewClientPool(idleTimeout time.Duration) *ClientPool {
+	return &ClientPool{
+		idleTimeout:  idleTimeout,
+		entries:      make(map[clientPoolKey]*clientPoolEntry),
+		createClient: CreateClientWithServiceAccountToken,
+	}
+}
+
+// Get returns the client for the given Vault role from the pool. If the pool
+// doesn't contain a client for the role a new client is created. The namespace
+// and the name of the service account must be set, when the serviceAccountToken
+// function is provided, so that every service account gets its own client.
+func (p *ClientPool) Get(vaultRole, namespace, serviceAccountName string, serviceAccountToken ServiceAccountToken) (*Client, error) {
+	key := clientPoolKey{vaultRole: vaultRole, namespace: namespace, serviceAccountName: serviceAccountName}
+
+	for {
+		p.mu.Lock()
+		entry, ok := p.entries[key]
+		if !ok {
+			entry = &clientPoolEntry{}
+			p.entries[key] = entry
+		}
+		p.mu.Unlock()
+
+		entry.mu.Lock()
+
+		// The entry was evicted while we were waiting for the lock, so that we
+		// have to get a new entry from the pool.
+		if entry.evicted {
+			entry.mu.Unlock()
+			continue
+		}
+
+		if entry.client == nil {
+			client, err := p.createClient(vaultRole, serviceAccountToken)
+			if err == nil && client == nil {
+				err = fmt.Errorf("could not create client for Vault role %s", vaultRole)
+			}
+			if err != nil {
+				// Remove the entry, so that the next call tries to create a new
+				// client.
+				entry.evicted = true
+				p.remove(key, entry)
+				entry.mu.Unlock()
+				return nil, err
+			}
+
+			now := time.Now()
+			entry.client = client
+			entry.renewal = newTokenRenewal(now)
+			entry.renewAt = now.Add(client.tokenRenewalDelay(time.Duration(client.getTokenLeaseDuration()) * time.Second))
+		}
+
+		entry.lastUsed = time.Now()
+		client := entry.client
+		entry.mu.Unlock()
+
+		return client, nil
+	}
+}
+
+// Start runs the renewal of the tokens and the eviction of idle clients until
+// the given context is canceled. When the context is canceled the tokens of all
+// clients are revoked.
+func (p *ClientPool) Start(ctx context.Context) error {
+	ticker := time.NewTicker(clientPoolCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.close()
+			return nil
+		case now := <-ticker.C:
+			p.maintain(now)
+		}
+	}
+}
+
+// NeedLeaderElection returns false, so that the pool is also started when the
+// operator is not the leader. The pool is only filled by the reconciliations,
+// which are only running on the leader.
+func (p *ClientPool) NeedLeaderElection() bool {
+	return false
+}
+
+// maintain evicts the clients which were not used for the idle timeout and
+// which have no valid leases. The tokens of the other clients are renewed, when
+// the renewal interval is reached. Clients which tokens can not be renewed and
+// where the login fails are evicted as well, so that a new client is created by
+// the next call of Get.
+func (p *ClientPool) maintain(now time.Time) {
+	p.mu.Lock()
+	entries := maps.Clone(p.entries)
+	p.mu.Unlock()
+
+	for key, entry := range entries {
+		entry.mu.Lock()
+
+		switch {
+		case entry.evicted || entry.client == nil:
+		case now.Sub(entry.lastUsed) >= p.idleTimeout && !entry.client.hasValidLeases(now):
+			log.Info("Evict idle Vault client", "vaultRole", key.vaultRole, "namespace", key.namespace, "serviceAccountName", key.serviceAccountName)
+			p.evict(key, entry, true)
+		case entry.client.renewToken && !now.Before(entry.renewAt):
+			delay, err := entry.client.renewTokenOnce(entry.renewal, now)
+			if err != nil {
+				// The token is not revoked, because it can still be used by a
+				// running reconciliation and expires on its own.
+				log.Error(err, "Could not renew token of Vault client", "vaultRole", key.vaultRole, "namespace", key.namespace, "serviceAccountName", key.serviceAccountName)
+				p.evict(key, entry, false)
+				break
+			}
+			entry.renewAt = now.Add(delay)
+		}
+
+		entry.mu.Unlock()
+	}
+}
+
+// close evicts all clients from the pool and revokes the tokens of the clients
+// without valid leases. The tokens of the other clients are not revoked, so
+// that the leases stay valid until the token expires, when the operator is
+// restarted.
+func (p *ClientPool) close() {
+	p.mu.Lock()
+	entries := maps.Clone(p.entries)
+	p.mu.Unlock()
+
+	now := time.Now()
+	for key, entry := range entries {
+		entry.mu.Lock()
+		if !entry.evicted && entry.client != nil {
+			p.evict(key, entry, !entry.client.hasValidLeases(now))
+		}
+		entry.mu.Unlock()
+	}
+}
+
+// evict removes the entry from the pool and revokes the token of the client,
+// when revoke is true. The token is only revoked, when it was created by the
+// login of the client. Tokens which were provided via the token auth method are
+// never revoked. The mutex of the entry must be held by the caller.
+func (p *ClientPool) evict(key clientPoolKey, entry *clientPoolEntry, revoke bool) {
+	entry.evicted = true
+	p.remove(key, entry)
+
+	if revoke && entry.client.requestToken != nil {
+		if err := entry.client.revokeToken(); err != nil {
+			log.Error(err, "Could not revoke token of Vault client", "vaultRole", key.vaultRole, "namespace", key.namespace, "serviceAccountName", key.serviceAccountName)
+		}
+	}
+}
+
+// remove removes the entry from the pool, if it was not already replaced by a
+// new entry.
+func (p *ClientPool) remove(key clientPoolKey, entry *clientPoolEntry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.entries[key] == entry {
+		delete(p.entries, key)
+	}
+}
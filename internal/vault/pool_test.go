@@ -0,0 +1,243 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testClientPoolServer is a Vault server for the client pool tests, which
+// counts the logins, renewals and revocations of tokens.
+type testClientPoolServer struct {
+	mu        sync.Mutex
+	logins    int
+	renewals  int
+	revoked   []string
+	failRenew bool
+}
+
+func (s *testClientPoolServer) handler(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.URL.Path {
+	case "/v1/auth/kubernetes/login":
+		s.logins++
+		_, _ = fmt.Fprintf(w, `{"auth": {"client_token": "token-%d", "lease_duration": 3600, "renewable": true}}`, s.logins)
+	case "/v1/auth/token/renew-self":
+		if s.failRenew {
+			http.Error(w, `{"errors": ["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		s.renewals++
+		_, _ = w.Write([]byte(`{"auth": {"client_token": "renewed", "lease_duration": 3600, "renewable": true}}`))
+	case "/v1/database/creds/app":
+		_, _ = w.Write([]byte(`{"lease_id": "database/creds/app/1234", "lease_duration": 7200, "renewable": true, "data": {"username": "app", "password": "secret"}}`))
+	case "/v1/auth/token/revoke-self":
+		s.revoked = append(s.revoked, r.Header.Get("X-Vault-Token"))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// newTestClientPool returns a client pool, which creates clients for the
+// given Vault server. The clients login like the clients for the Kubernetes
+// auth method.
+func newTestClientPool(t *testing.T, url string, idleTimeout time.Duration) *ClientPool {
+	t.Helper()
+
+	pool := NewClientPool(idleTimeout)
+	pool.createClient = func(vaultRole string, _ ServiceAccountToken) (*Client, error) {
+		c := newTestClient(t, url)
+		c.renewToken = true
+		c.tokenMaxTTL = 24 * 60 * 60
+		c.requestToken = func(c *Client) error {
+			secret, err := c.client.Logical().Write("auth/kubernetes/login", map[string]any{"role": vaultRole})
+			if err != nil {
+				return err
+			}
+			return c.setToken(secret.Auth, "")
+		}
+
+		if err := c.requestToken(c); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+
+	return pool
+}
+
+// TestClientPoolGet verifies that the clients are reused for the same Vault
+// role and service account.
+func TestClientPoolGet(t *testing.T) {
+	server := &testClientPoolServer{}
+	srv := httptest.NewServer(http.HandlerFunc(server.handler))
+	defer srv.Close()
+
+	pool := newTestClientPool(t, srv.URL, time.Hour)
+
+	first, err := pool.Get("team-a", "", "", nil)
+	if err != nil {
+		t.Fatalf("Get returned an error: %v", err)
+	}
+	second, err := pool.Get("team-a", "", "", nil)
+	if err != nil {
+		t.Fatalf("Get returned an error: %v", err)
+	}
+	if first != second {
+		t.Error("expected the same client for the same Vault role")
+	}
+
+	other, err := pool.Get("team-a", "team-a", "default", nil)
+	if err != nil {
+		t.Fatalf("Get returned an error: %v", err)
+	}
+	if other == first {
+		t.Error("expected a new client for another service account")
+	}
+
+	if server.logins != 2 {
+		t.Errorf("logins = %d, want 2", server.logins)
+	}
+}
+
+// TestClientPoolMaintain verifies that the tokens are renewed, that a new token
+// is requested when the renewal fails and that idle clients are evicted and
+// their tokens are revoked.
+func TestClientPoolMaintain(t *testing.T) {
+	server := &testClientPoolServer{}
+	srv := httptest.NewServer(http.HandlerFunc(server.handler))
+	defer srv.Close()
+
+	pool := newTestClientPool(t, srv.URL, 2*time.Hour)
+
+	client, err := pool.Get("team-a", "", "", nil)
+	if err != nil {
+		t.Fatalf("Get returned an error: %v", err)
+	}
+
+	now := time.Now()
+	pool.maintain(now)
+	if server.renewals != 0 {
+		t.Errorf("renewals = %d, want 0 before the renewal interval is reached", server.renewals)
+	}
+
+	now = now.Add(31 * time.Minute)
+	pool.maintain(now)
+	if server.renewals != 1 {
+		t.Errorf("renewals = %d, want 1", server.renewals)
+	}
+
+	server.failRenew = true
+	now = now.Add(31 * time.Minute)
+	pool.maintain(now)
+	if server.logins != 2 {
+		t.Errorf("logins = %d, want 2 after the renewal failed", server.logins)
+	}
+	if token := client.client.Token(); token != "token-2" {
+		t.Errorf("token = %q, want token-2", token)
+	}
+
+	now = now.Add(2 * time.Hour)
+	pool.maintain(now)
+	if len(server.revoked) != 1 || server.revoked[0] != "token-2" {
+		t.Errorf("revoked = %v, want [token-2]", server.revoked)
+	}
+
+	if _, err := pool.Get("team-a", "", "", nil); err != nil {
+		t.Fatalf("Get returned an error: %v", err)
+	}
+	if server.logins != 3 {
+		t.Errorf("logins = %d, want 3 after the client was evicted", server.logins)
+	}
+}
+
+// TestClientPoolStart verifies that the tokens of all clients are revoked, when
+// the pool is stopped.
+func TestClientPoolStart(t *testing.T) {
+	server := &testClientPoolServer{}
+	srv := httptest.NewServer(http.HandlerFunc(server.handler))
+	defer srv.Close()
+
+	pool := newTestClientPool(t, srv.URL, time.Hour)
+
+	for _, role := range []string{"team-a", "team-b"} {
+		if _, err := pool.Get(role, "", "", nil); err != nil {
+			t.Fatalf("Get returned an error: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := pool.Start(ctx); err != nil {
+		t.Fatalf("Start returned an error: %v", err)
+	}
+
+	if len(server.revoked) != 2 {
+		t.Errorf("revoked = %v, want 2 tokens", server.revoked)
+	}
+	if len(pool.entries) != 0 {
+		t.Errorf("entries = %d, want 0", len(pool.entries))
+	}
+}
+
+// TestClientPoolLeases verifies that clients which created leases are kept in
+// the pool until the leases expired and that their tokens are not revoked
+// before, because Vault would also revoke the leases.
+func TestClientPoolLeases(t *testing.T) {
+	server := &testClientPoolServer{}
+	srv := httptest.NewServer(http.HandlerFunc(server.handler))
+	defer srv.Close()
+
+	pool := newTestClientPool(t, srv.URL, 15*time.Minute)
+
+	client, err := pool.Get("team-a", "", "", nil)
+	if err != nil {
+		t.Fatalf("Get returned an error: %v", err)
+	}
+	if _, _, err := client.GetDatabaseCredentials(context.Background(), "database", "app", ""); err != nil {
+		t.Fatalf("GetDatabaseCredentials returned an error: %v", err)
+	}
+	if _, err := pool.Get("team-b", "", "", nil); err != nil {
+		t.Fatalf("Get returned an error: %v", err)
+	}
+
+	now := time.Now().Add(time.Hour)
+	pool.maintain(now)
+	if len(server.revoked) != 1 || server.revoked[0] != "token-2" {
+		t.Errorf("revoked = %v, want [token-2]", server.revoked)
+	}
+	if server.renewals != 1 {
+		t.Errorf("renewals = %d, want 1 for the client with a valid lease", server.renewals)
+	}
+	if second, err := pool.Get("team-a", "", "", nil); err != nil || second != client {
+		t.Errorf("expected the client with a valid lease to be kept in the pool")
+	}
+
+	pool.close()
+	if len(server.revoked) != 1 {
+		t.Errorf("revoked = %v, want no revocation of the token with a valid lease on shutdown", server.revoked)
+	}
+
+	pool = newTestClientPool(t, srv.URL, 15*time.Minute)
+	client, err = pool.Get("team-a", "", "", nil)
+	if err != nil {
+		t.Fatalf("Get returned an error: %v", err)
+	}
+	if _, _, err := client.GetDatabaseCredentials(context.Background(), "database", "app", ""); err != nil {
+		t.Fatalf("GetDatabaseCredentials returned an error: %v", err)
+	}
+
+	pool.maintain(time.Now().Add(3 * time.Hour))
+	if len(server.revoked) != 2 || server.revoked[1] != "token-3" {
+		t.Errorf("revoked = %v, want token-3 to be revoked after the lease expired", server.revoked)
+	}
+}
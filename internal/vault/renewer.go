@@ -0,0 +1,193 @@
+package vault
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	// minTokenRenewalDelay is the minimum time between two renewals of a
+	// token, so that tokens with a very short or without a TTL are not renewed
+	// in a tight loop.
+	minTokenRenewalDelay = 5 * time.Second
+
+	// minTokenRenewalBackoff is the time until a failed renewal is retried for
+	// the first time. The time is doubled for every further failed attempt.
+	minTokenRenewalBackoff = time.Second
+)
+
+// TokenRenewer renews the token of a Vault client until the manager is
+// stopped. It implements the Runnable interface of the controller-runtime, so
+// that it can be added to the manager.
+type TokenRenewer struct {
+	client *Client
+}
+
+// NewTokenRenewer returns a new renewer for the token of the given client.
+func NewTokenRenewer(client *Client) *TokenRenewer {
+	return &TokenRenewer{client: client}
+}
+
+// Start renews the token of the client until the given context is canceled.
+func (r *TokenRenewer) Start(ctx context.Context) error {
+	return r.client.RenewToken(ctx)
+}
+
+// NeedLeaderElection returns false, because the token must also be renewed
+// when the operator is not the leader, so that the health checks do not fail
+// and the token is valid when the operator becomes the leader.
+func (r *TokenRenewer) NeedLeaderElection() bool {
+	return false
+}
+
+// tokenRenewal is the state of the renewal of a token.
+type tokenRenewal struct {
+	// issued is the time of the last login, which is used to request a new
+	// token when the maximum lifetime of the token is reached.
+	issued time.Time
+	// renewable is false, when the token can not be renewed anymore, so that
+	// a new token must be requested.
+	renewable bool
+}
+
+// newTokenRenewal returns the renewal state for a token, which was issued at
+// the given time.
+func newTokenRenewal(issued time.Time) *tokenRenewal {
+	return &tokenRenewal{issued: issued, renewable: true}
+}
+
+// RenewToken renews the token of the client until the given context is
+// canceled. Like the LifetimeWatcher of the Vault API client, the renewals are
+// scheduled based on the TTL of the token, which is returned by Vault. Failed
+// renewals are retried with an exponential backoff. When the token can not be
+// renewed anymore a new token is requested immediately.
+func (c *Client) RenewToken(ctx context.Context) error {
+	state := newTokenRenewal(time.Now())
+
+	for {
+		delay, err := c.renewTokenOnce(state, time.Now())
+		if err != nil {
+			log.Error(err, "Could not renew token", "retryIn", delay.String())
+		}
+
+		if !c.waitTokenRenewal(ctx, delay) {
+			return nil
+		}
+	}
+}
+
+// renewTokenOnce renews the token of the client and returns the time until the
+// token should be renewed again. A new token is requested instead, when the
+// maximum lifetime of the token is reached, the client certificate for the
+// cert auth method was rotated or the token can not be renewed anymore. The
+// latter is also detected by the renewal itself, in which case the new token is
+// requested in the same call. If the renewal fails the returned time is the
+// backoff until the next attempt.
+func (c *Client) renewTokenOnce(state *tokenRenewal, now time.Time) (time.Duration, error) {
+	relogin := c.requestToken != nil && (!state.renewable || (c.tokenMaxTTL > 0 && now.Sub(state.issued) >= time.Duration(c.tokenMaxTTL)*time.Second) || c.certFiles.changed())
+
+	if !relogin {
+		log.Info("Renew Vault token")
+		increment := c.getTokenLeaseDuration()
+		start := time.Now()
+		secret, err := c.client.Auth().Token().RenewSelf(increment)
+		observeRequest(requestOperationRenew, "auth/token", start, err)
+		if err != nil {
+			if c.requestToken == nil {
+				return c.tokenRenewalBackoff(c.renewTokenFailed()), err
+			}
+			log.Error(err, "Could not renew token, request new token")
+		} else {
+			ttl, _ := secret.TokenTTL()
+			renewable, _ := secret.TokenIsRenewable()
+
+			c.tokenRenewed(ttl)
+
+			// When the returned TTL is shorter than the requested increment,
+			// the token reached the maximum TTL of Vault and is replaced by a
+			// new token right away, so that it can not expire while waiting
+			// for the next renewal.
+			if renewable && (increment <= 0 || ttl >= time.Duration(increment)*time.Second) {
+				c.renewTokenSucceeded()
+				return c.tokenRenewalDelay(ttl), nil
+			}
+
+			log.Info("Vault token can not be renewed anymore", "ttl", ttl.String())
+			state.renewable = false
+			if c.requestToken == nil {
+				c.renewTokenSucceeded()
+				return c.tokenRenewalDelay(ttl), nil
+			}
+		}
+	}
+
+	log.Info("Request new Vault token")
+	if err := c.relogin(); err != nil {
+		return c.tokenRenewalBackoff(c.renewTokenFailed()), err
+	}
+
+	state.issued = now
+	state.renewable = true
+	c.renewTokenSucceeded()
+	return c.tokenRenewalDelay(time.Duration(c.getTokenLeaseDuration()) * time.Second), nil
+}
+
+// tokenRenewalDelay returns the time until a token with the given TTL should be
+// renewed. Like the LifetimeWatcher of the Vault API client, the token is
+// renewed after two thirds of its TTL reduced by a random jitter of up to 10%
+// of the TTL, so that not all tokens are renewed at the same time. If the
+// configured renewal interval is shorter, it is used instead.
+func (c *Client) tokenRenewalDelay(ttl time.Duration) time.Duration {
+	delay := time.Duration(float64(ttl) * (2.0/3.0 - rand.Float64()*0.1)) //nolint:gosec
+
+	if interval := c.getTokenRenewalInterval(); interval > 0 && (delay <= 0 || interval < delay) {
+		delay = interval
+	}
+
+	return max(delay, minTokenRenewalDelay)
+}
+
+// tokenRenewalBackoff returns the time until a failed renewal is retried. The
+// time grows exponentially with the number of failed attempts and is limited by
+// the configured retry interval. A random jitter of up to 20% is subtracted, so
+// that the retries of multiple clients are spread.
+func (c *Client) tokenRenewalBackoff(attempts int) time.Duration {
+	backoff := minTokenRenewalBackoff << min(max(attempts-1, 0), 16)
+
+	if maxBackoff := time.Duration(c.tokenRenewalRetryInterval * float64(time.Second)); maxBackoff > 0 && backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+
+	return backoff - time.Duration(rand.Float64()*0.2*float64(backoff)) //nolint:gosec
+}
+
+// waitTokenRenewal waits for the given duration and returns true, when the
+// token should be renewed. It returns false, when the context was canceled.
+// When the cert auth method is used, the files of the client certificate are
+// checked every certFilesCheckInterval and the wait ends as soon as they were
+// changed, so that a new token is requested with the rotated certificate.
+func (c *Client) waitTokenRenewal(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	var check <-chan time.Time
+	if c.certFiles != nil {
+		ticker := time.NewTicker(certFilesCheckInterval)
+		defer ticker.Stop()
+		check = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer.C:
+			return true
+		case <-check:
+			if c.certFiles.changed() {
+				return true
+			}
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testRenewerServer is a Vault server for the token renewal tests, which
+// returns the configured TTL for renewed tokens.
+type testRenewerServer struct {
+	mu        sync.Mutex
+	logins    int
+	renewals  int
+	renewTTL  int
+	renewable bool
+	failRenew bool
+}
+
+func (s *testRenewerServer) handler(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.URL.Path {
+	case "/v1/auth/approle/login":
+		s.logins++
+		_, _ = fmt.Fprintf(w, `{"auth": {"client_token": "token-%d", "lease_duration": 3600, "renewable": true}}`, s.logins)
+	case "/v1/auth/token/renew-self":
+		if s.failRenew {
+			http.Error(w, `{"errors": ["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		s.renewals++
+		_, _ = fmt.Fprintf(w, `{"auth": {"client_token": "token-%d", "lease_duration": %d, "renewable": %t}}`, s.logins, s.renewTTL, s.renewable)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// newTestRenewerClient returns a client for the given Vault server, which can
+// request new tokens via the AppRole login endpoint.
+func newTestRenewerClient(t *testing.T, url string, login bool) *Client {
+	t.Helper()
+
+	c := newTestClient(t, url)
+	c.tokenLeaseDuration = 3600
+	c.tokenRenewalInterval = 1800
+	c.tokenRenewalRetryInterval = 30
+	if login {
+		c.requestToken = func(c *Client) error {
+			secret, err := c.client.Logical().Write("auth/approle/login", nil)
+			if err != nil {
+				return err
+			}
+			return c.setToken(secret.Auth, "")
+		}
+	}
+
+	return c
+}
+
+// TestRenewTokenOnce verifies that the renewals are scheduled based on the TTL
+// returned by Vault and that a new token is requested, when the token can not
+// be renewed anymore.
+func TestRenewTokenOnce(t *testing.T) {
+	t.Run("renewal is scheduled based on the returned TTL", func(t *testing.T) {
+		server := &testRenewerServer{renewTTL: 3600, renewable: true}
+		srv := httptest.NewServer(http.HandlerFunc(server.handler))
+		defer srv.Close()
+
+		c := newTestRenewerClient(t, srv.URL, true)
+		c.tokenRenewalInterval = 0
+		state := newTokenRenewal(time.Now())
+
+		delay, err := c.renewTokenOnce(state, time.Now())
+		if err != nil {
+			t.Fatalf("renewTokenOnce returned an error: %v", err)
+		}
+		if lower, upper := 34*time.Minute, 40*time.Minute; delay < lower || delay > upper {
+			t.Errorf("delay = %s, want between %s and %s", delay, lower, upper)
+		}
+		if server.renewals != 1 || server.logins != 0 {
+			t.Errorf("renewals = %d, logins = %d, want 1 and 0", server.renewals, server.logins)
+		}
+	})
+
+	t.Run("configured renewal interval is used when it is shorter", func(t *testing.T) {
+		server := &testRenewerServer{renewTTL: 3600, renewable: true}
+		srv := httptest.NewServer(http.HandlerFunc(server.handler))
+		defer srv.Close()
+
+		c := newTestRenewerClient(t, srv.URL, true)
+		delay, err := c.renewTokenOnce(newTokenRenewal(time.Now()), time.Now())
+		if err != nil {
+			t.Fatalf("renewTokenOnce returned an error: %v", err)
+		}
+		if delay != 30*time.Minute {
+			t.Errorf("delay = %s, want 30m", delay)
+		}
+	})
+
+	t.Run("new token is requested when the max TTL is reached", func(t *testing.T) {
+		server := &testRenewerServer{renewTTL: 60, renewable: true}
+		srv := httptest.NewServer(http.HandlerFunc(server.handler))
+		defer srv.Close()
+
+		c := newTestRenewerClient(t, srv.URL, true)
+		state := newTokenRenewal(time.Now())
+
+		if _, err := c.renewTokenOnce(state, time.Now()); err != nil {
+			t.Fatalf("renewTokenOnce returned an error: %v", err)
+		}
+		if server.logins != 1 || server.renewals != 1 {
+			t.Errorf("logins = %d, renewals = %d, want 1 and 1", server.logins, server.renewals)
+		}
+		if !state.renewable {
+			t.Error("expected the new token to be renewable")
+		}
+	})
+
+	t.Run("new token is requested when the token is not renewable", func(t *testing.T) {
+		server := &testRenewerServer{renewTTL: 3600, renewable: false}
+		srv := httptest.NewServer(http.HandlerFunc(server.handler))
+		defer srv.Close()
+
+		c := newTestRenewerClient(t, srv.URL, true)
+		if _, err := c.renewTokenOnce(newTokenRenewal(time.Now()), time.Now()); err != nil {
+			t.Fatalf("renewTokenOnce returned an error: %v", err)
+		}
+		if server.logins != 1 || server.renewals != 1 {
+			t.Errorf("logins = %d, renewals = %d, want 1 and 1", server.logins, server.renewals)
+		}
+	})
+
+	t.Run("token without auth method is used until it expires", func(t *testing.T) {
+		server := &testRenewerServer{renewTTL: 60, renewable: true}
+		srv := httptest.NewServer(http.HandlerFunc(server.handler))
+		defer srv.Close()
+
+		c := newTestRenewerClient(t, srv.URL, false)
+		state := newTokenRenewal(time.Now())
+
+		delay, err := c.renewTokenOnce(state, time.Now())
+		if err != nil {
+			t.Fatalf("renewTokenOnce returned an error: %v", err)
+		}
+		if delay > 40*time.Second {
+			t.Errorf("delay = %s, want at most 40s for a TTL of 60s", delay)
+		}
+		if server.logins != 0 || server.renewals != 1 {
+			t.Errorf("logins = %d, renewals = %d, want 0 and 1", server.logins, server.renewals)
+		}
+	})
+
+	t.Run("new token is requested when the renewal fails", func(t *testing.T) {
+		server := &testRenewerServer{failRenew: true}
+		srv := httptest.NewServer(http.HandlerFunc(server.handler))
+		defer srv.Close()
+
+		c := newTestRenewerClient(t, srv.URL, true)
+		if _, err := c.renewTokenOnce(newTokenRenewal(time.Now()), time.Now()); err != nil {
+			t.Fatalf("renewTokenOnce returned an error: %v", err)
+		}
+		if server.logins != 1 {
+			t.Errorf("logins = %d, want 1", server.logins)
+		}
+		if token := c.client.Token(); token != "token-1" {
+			t.Errorf("token = %q, want token-1", token)
+		}
+	})
+
+	t.Run("failed renewal is retried with backoff", func(t *testing.T) {
+		server := &testRenewerServer{failRenew: true}
+		srv := httptest.NewServer(http.HandlerFunc(server.handler))
+		defer srv.Close()
+
+		c := newTestRenewerClient(t, srv.URL, false)
+		state := newTokenRenewal(time.Now())
+
+		for i := range 3 {
+			delay, err := c.renewTokenOnce(state, time.Now())
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if want := minTokenRenewalBackoff << i; delay > want || delay < want*8/10 {
+				t.Errorf("delay = %s, want about %s", delay, want)
+			}
+		}
+		if err := c.GetHealth(time.Minute); err != nil {
+			t.Errorf("expected the health check to succeed, because the renewal is failing for less than a minute: %v", err)
+		}
+		if err := c.GetHealth(0); err == nil {
+			t.Error("expected the health check to fail after the failed renewals")
+		}
+	})
+}
+
+// TestGetHealth verifies that the health check fails when the renewal of the
+// token is failing for at least the given threshold and succeeds again after a
+// successful renewal.
+func TestGetHealth(t *testing.T) {
+	c := newTestClient(t, "http://127.0.0.1:0")
+
+	if err := c.GetHealth(0); err != nil {
+		t.Errorf("expected the health check to succeed without failed renewals: %v", err)
+	}
+
+	c.renewTokenFailed()
+	c.mu.Lock()
+	c.firstFailedRenewTokenAttempt = time.Now().Add(-10 * time.Minute)
+	c.mu.Unlock()
+	c.renewTokenFailed()
+
+	if err := c.GetHealth(5 * time.Minute); err == nil {
+		t.Error("expected the health check to fail, because the renewal is failing for 10 minutes")
+	}
+	if err := c.GetHealth(15 * time.Minute); err != nil {
+		t.Errorf("expected the health check to succeed, because the renewal is failing for less than 15 minutes: %v", err)
+	}
+
+	c.renewTokenSucceeded()
+	if err := c.GetHealth(0); err != nil {
+		t.Errorf("expected the health check to succeed after a successful renewal: %v", err)
+	}
+}
+
+// TestTokenRenewalBackoff verifies that the backoff grows exponentially and is
+// limited by the retry interval.
+func TestTokenRenewalBackoff(t *testing.T) {
+	c := &Client{tokenRenewalRetryInterval: 30}
+
+	for _, tc := range []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: time.Second},
+		{attempts: 2, want: 2 * time.Second},
+		{attempts: 4, want: 8 * time.Second},
+		{attempts: 6, want: 30 * time.Second},
+		{attempts: 100, want: 30 * time.Second},
+	} {
+		if got := c.tokenRenewalBackoff(tc.attempts); got > tc.want || got < tc.want*8/10 {
+			t.Errorf("backoff for %d attempts = %s, want about %s", tc.attempts, got, tc.want)
+		}
+	}
+}
+
+// TestRenewTokenContextCanceled verifies that the token renewal stops, when the
+// context is canceled.
+func TestRenewTokenContextCanceled(t *testing.T) {
+	server := &testRenewerServer{renewTTL: 3600, renewable: true}
+	srv := httptest.NewServer(http.HandlerFunc(server.handler))
+	defer srv.Close()
+
+	c := newTestRenewerClient(t, srv.URL, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- NewTokenRenewer(c).Start(ctx)
+	}()
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Start returned an error: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("token renewal was not stopped")
+	}
+}
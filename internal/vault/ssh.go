@@ -0,0 +1,61 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ricoberger/vault-secrets-operator/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SignSSHKey signs the given public key with the given role of the SSH secrets
+// engine mounted under the given path. The options (e.g. "ttl",
+// "valid_principals" and "cert_type") are passed to Vault. The returned data
+// contains the signed certificate ("signed_key") and its serial number
+// ("serial_number").
+func (c *Client) SignSSHKey(ctx context.Context, path string, role string, publicKey string, options map[string]string, vaultNamespace string) (_ map[string][]byte, err error) {
+	log.Info(fmt.Sprintf("Sign ssh key with %s/sign/%s", path, role))
+
+	ctx, span := tracer.Start(ctx, "SignSSHKey", trace.WithAttributes(attribute.String("vault.path", path), attribute.String("vault.role", role)))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	client, err := c.namespacedClient(vaultNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	optionsI := make(map[string]any, len(options)+1)
+	for k, v := range options {
+		optionsI[k] = v
+	}
+	optionsI["public_key"] = publicKey
+
+	start := time.Now()
+	r, err := client.Logical().WriteWithContext(ctx, path+"/sign/"+role, optionsI)
+	observeRequest(requestOperationSSHSign, path, start, err)
+	setRequestID(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	if r == nil {
+		return nil, fmt.Errorf("signed key is nil")
+	}
+
+	data, err := convertData(r.Data, []string{
+		"serial_number",
+		"signed_key",
+	}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data["signed_key"]) == 0 {
+		return nil, fmt.Errorf("missing signed key")
+	}
+
+	return data, nil
+}
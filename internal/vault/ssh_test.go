@@ -0,0 +1,70 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestSignSSHKey verifies that the public key and the engine options are sent
+// to the sign endpoint of the SSH secrets engine and that the signed key is
+// returned.
+func TestSignSSHKey(t *testing.T) {
+	var (
+		gotPath string
+		gotBody map[string]any
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"data": {
+				"serial_number": "c73f26d2340276aa",
+				"signed_key": "ssh-ed25519-cert-v01@openssh.com AAAAIHNzaC1lZDI1NTE5LWNlcnQtdjAxQG9wZW5zc2guY29t..."
+			}
+		}`))
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv.URL)
+
+	data, err := client.SignSSHKey(context.Background(), "ssh-client-signer", "bastion", "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIB", map[string]string{
+		"valid_principals": "ubuntu",
+		"ttl":              "30m",
+	}, "")
+	if err != nil {
+		t.Fatalf("SignSSHKey returned an error: %v", err)
+	}
+
+	if gotPath != "/v1/ssh-client-signer/sign/bastion" {
+		t.Errorf("path = %q, want /v1/ssh-client-signer/sign/bastion", gotPath)
+	}
+	if got := gotBody["public_key"]; got != "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIB" {
+		t.Errorf("public_key = %v", got)
+	}
+	if got := gotBody["valid_principals"]; got != "ubuntu" {
+		t.Errorf("valid_principals = %v, want ubuntu", got)
+	}
+	if got := string(data["serial_number"]); got != "c73f26d2340276aa" {
+		t.Errorf("serial_number = %q, want c73f26d2340276aa", got)
+	}
+	if len(data["signed_key"]) == 0 {
+		t.Error("expected a signed key")
+	}
+}
+
+// TestSignSSHKeyNamespace verifies that the namespace of a secret is used to
+// sign the public key.
+func TestSignSSHKeyNamespace(t *testing.T) {
+	client, namespaces := newTestNamespaceClient(t, `{"data": {"signed_key": "ssh-ed25519-cert-v01@openssh.com AAAA", "serial_number": "1234"}}`)
+
+	if _, err := client.SignSSHKey(context.Background(), "ssh-client-signer", "client", "ssh-ed25519 AAAA", nil, "team-a"); err != nil {
+		t.Fatalf("SignSSHKey returned an error: %v", err)
+	}
+
+	assertNamespaces(t, namespaces())
+}
@@ -0,0 +1,235 @@
+package vault
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// certFilesCheckInterval is the interval in which the files of the client
+// certificate are checked for changes, when the cert auth method is used.
+var certFilesCheckInterval = 10 * time.Second
+
+// tlsFiles is used to load the CA bundle and the client certificate for the
+// connection to Vault from files. The files are checked for changes before
+// every new TLS connection and reloaded when they were modified, so that
+// rotated certificates (e.g. by cert-manager) are used without a restart of the
+// operator.
+type tlsFiles struct {
+	caCertFile     string
+	clientCertFile string
+	clientKeyFile  string
+	// host is the host of the Vault address, which is used to verify the
+	// server certificate, when no server name was sent via SNI (e.g. when
+	// the address contains an IP address).
+	host string
+
+	mu            sync.Mutex
+	caCertModTime time.Time
+	rootCAs       *x509.CertPool
+	// certModTime and keyModTime are the modification times of the files of
+	// the client certificate, when they were loaded the last time. They are
+	// also updated when the files could not be loaded, so that a partially
+	// written key pair is only loaded again after the files were modified
+	// again.
+	certModTime time.Time
+	keyModTime  time.Time
+	clientCert  *tls.Certificate
+}
+
+// configureTLS configures the TLS settings of the HTTP client, which is used by
+// the Vault API client. The CA bundle and the client certificate are loaded
+// from the given files and reloaded when the files change. The server name is
+// used for SNI and the verification of the server certificate. Settings which
+// are not provided keep the defaults of the Vault API client, which can still
+// be configured via the VAULT_CACERT, VAULT_CLIENT_CERT, VAULT_CLIENT_KEY,
+// VAULT_TLS_SERVER_NAME and VAULT_SKIP_VERIFY environment variables.
+func configureTLS(config *api.Config, caCertFile, clientCertFile, clientKeyFile, serverName string) (*tlsFiles, error) {
+	if caCertFile == "" && clientCertFile == "" && clientKeyFile == "" && serverName == "" {
+		return nil, nil
+	}
+
+	if (clientCertFile == "") != (clientKeyFile == "") {
+		return nil, fmt.Errorf("client certificate and client key must be set together")
+	}
+
+	transport, ok := config.HttpClient.Transport.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("unsupported transport for the Vault client")
+	}
+
+	files := &tlsFiles{
+		caCertFile:     caCertFile,
+		clientCertFile: clientCertFile,
+		clientKeyFile:  clientKeyFile,
+		host:           serverName,
+	}
+	if files.host == "" {
+		if address, err := url.Parse(config.Address); err == nil {
+			files.host = address.Hostname()
+		}
+	}
+
+	// The TLS config is modified in place, because it is shared with the HTTP/2
+	// transport, which was already configured by the Vault API client.
+	tlsConfig := transport.TLSClientConfig
+	if serverName != "" {
+		tlsConfig.ServerName = serverName
+	}
+
+	if clientCertFile != "" {
+		if _, err := files.getClientCertificate(nil); err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = nil
+		tlsConfig.GetClientCertificate = files.getClientCertificate
+	}
+
+	if caCertFile != "" {
+		if _, err := files.getRootCAs(); err != nil {
+			return nil, err
+		}
+
+		// The root CAs of a TLS config can not be changed for new connections,
+		// so that we have to verify the server certificate on our own, to use
+		// the reloaded CA bundle. The standard verification is disabled, but
+		// the certificate chain and the server name are still verified in
+		// VerifyConnection.
+		if !tlsConfig.InsecureSkipVerify {
+			tlsConfig.InsecureSkipVerify = true //nolint:gosec
+			tlsConfig.VerifyConnection = files.verifyConnection
+		}
+	}
+
+	return files, nil
+}
+
+// changed returns true if the client certificate or the client key was
+// modified since it was loaded the last time. Files which could not be loaded
+// are not reported as changed again, until they are modified again.
+func (f *tlsFiles) changed() bool {
+	if f == nil || f.clientCertFile == "" {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return modTime(f.clientCertFile) != f.certModTime || modTime(f.clientKeyFile) != f.keyModTime
+}
+
+// getClientCertificate returns the client certificate and reloads it, when the
+// files were modified.
+func (f *tlsFiles) getClientCertificate(_ *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	certModTime := modTime(f.clientCertFile)
+	keyModTime := modTime(f.clientKeyFile)
+	if f.clientCert != nil && certModTime == f.certModTime && keyModTime == f.keyModTime {
+		return f.clientCert, nil
+	}
+
+	f.certModTime = certModTime
+	f.keyModTime = keyModTime
+
+	cert, err := tls.LoadX509KeyPair(f.clientCertFile, f.clientKeyFile)
+	if err != nil {
+		// Keep using the old certificate, when the files are rotated and not
+		// all files were written yet. The certificate is loaded again, when
+		// the remaining files were written.
+		if f.clientCert != nil {
+			log.Error(err, "Could not reload client certificate for Vault")
+			return f.clientCert, nil
+		}
+		return nil, fmt.Errorf("could not load client certificate for Vault: %w", err)
+	}
+
+	if f.clientCert != nil {
+		log.Info("Reloaded client certificate for Vault")
+	}
+
+	f.clientCert = &cert
+	return f.clientCert, nil
+}
+
+// getRootCAs returns the CA bundle and reloads it, when the file was modified.
+func (f *tlsFiles) getRootCAs() (*x509.CertPool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	caCertModTime := modTime(f.caCertFile)
+	if f.rootCAs != nil && caCertModTime == f.caCertModTime {
+		return f.rootCAs, nil
+	}
+
+	//nolint:gosec
+	caCert, err := os.ReadFile(f.caCertFile)
+	if err == nil {
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(caCert) {
+			err = fmt.Errorf("no certificates found in %s", f.caCertFile)
+		} else {
+			if f.rootCAs != nil {
+				log.Info("Reloaded CA certificate for Vault")
+			}
+
+			f.rootCAs = rootCAs
+			f.caCertModTime = caCertModTime
+			return f.rootCAs, nil
+		}
+	}
+
+	if f.rootCAs != nil {
+		log.Error(err, "Could not reload CA certificate for Vault")
+		return f.rootCAs, nil
+	}
+	return nil, fmt.Errorf("could not load CA certificate for Vault: %w", err)
+}
+
+// verifyConnection verifies the certificate chain of the Vault server against
+// the (reloaded) CA bundle and the server name of the connection.
+func (f *tlsFiles) verifyConnection(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return fmt.Errorf("no server certificate provided")
+	}
+
+	rootCAs, err := f.getRootCAs()
+	if err != nil {
+		return err
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	serverName := cs.ServerName
+	if serverName == "" {
+		serverName = f.host
+	}
+
+	_, err = cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         rootCAs,
+		Intermediates: intermediates,
+		DNSName:       serverName,
+	})
+	return err
+}
+
+// modTime returns the modification time of the given file or the zero time, if
+// the file does not exist.
+func modTime(name string) time.Time {
+	info, err := os.Stat(name)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,299 @@
+package vault
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// testCertificate is a certificate and its private key for the TLS tests.
+type testCertificate struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCertificate creates a new certificate, which is signed by the given
+// parent certificate. If the parent is nil a self-signed CA certificate is
+// created.
+func newTestCertificate(t *testing.T, commonName string, parent *testCertificate, template *x509.Certificate) *testCertificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatalf("failed to generate serial number: %v", err)
+	}
+
+	template.SerialNumber = serialNumber
+	template.Subject = pkix.Name{CommonName: commonName}
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	signerCert, signerKey := template, key
+	if parent != nil {
+		signerCert, signerKey = parent.cert, parent.key
+	} else {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	return &testCertificate{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// writeTestFile writes the given data to the file and sets the modification
+// time, so that changes are detected even on file systems with a low
+// resolution of the modification time.
+func writeTestFile(t *testing.T, name string, data []byte, mtime time.Time) {
+	t.Helper()
+
+	if err := os.WriteFile(name, data, 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	if err := os.Chtimes(name, mtime, mtime); err != nil {
+		t.Fatalf("failed to set modification time of %s: %v", name, err)
+	}
+}
+
+// TestConfigureTLS verifies that the client certificate is sent to a Vault
+// server which requires mTLS, that the server certificate is verified against
+// the configured CA bundle and server name and that a rotated CA bundle is
+// used without creating a new client.
+func TestConfigureTLS(t *testing.T) {
+	ca := newTestCertificate(t, "vault-ca", nil, &x509.Certificate{})
+	otherCA := newTestCertificate(t, "other-ca", nil, &x509.Certificate{})
+	server := newTestCertificate(t, "vault.example.com", ca, &x509.Certificate{
+		DNSNames:    []string{"vault.example.com"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	client := newTestCertificate(t, "vault-secrets-operator", ca, &x509.Certificate{
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	serverCert, err := tls.X509KeyPair(server.certPEM, server.keyPEM)
+	if err != nil {
+		t.Fatalf("failed to load server certificate: %v", err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"username": "admin"}}`))
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+
+	mtime := time.Now().Add(-time.Minute)
+	writeTestFile(t, caFile, otherCA.certPEM, mtime)
+	writeTestFile(t, certFile, client.certPEM, mtime)
+	writeTestFile(t, keyFile, client.keyPEM, mtime)
+
+	config := api.DefaultConfig()
+	config.Address = srv.URL
+	if _, err := configureTLS(config, caFile, certFile, keyFile, "vault.example.com"); err != nil {
+		t.Fatalf("configureTLS returned an error: %v", err)
+	}
+	config.MaxRetries = 0
+
+	apiClient, err := api.NewClient(config)
+	if err != nil {
+		t.Fatalf("failed to create vault client: %v", err)
+	}
+
+	if _, err := apiClient.Logical().Read("kv/app"); err == nil {
+		t.Fatal("expected an error for a server certificate, which is not signed by the configured CA")
+	}
+
+	writeTestFile(t, caFile, ca.certPEM, time.Now())
+
+	secret, err := apiClient.Logical().Read("kv/app")
+	if err != nil {
+		t.Fatalf("failed to read secret after the CA bundle was rotated: %v", err)
+	}
+	if secret.Data["username"] != "admin" {
+		t.Errorf("username = %v, want admin", secret.Data["username"])
+	}
+}
+
+// TestConfigureTLSServerName verifies that the server certificate is verified
+// against the configured server name.
+func TestConfigureTLSServerName(t *testing.T) {
+	ca := newTestCertificate(t, "vault-ca", nil, &x509.Certificate{})
+	server := newTestCertificate(t, "vault.example.com", ca, &x509.Certificate{
+		DNSNames:    []string{"vault.example.com"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+
+	serverCert, err := tls.X509KeyPair(server.certPEM, server.keyPEM)
+	if err != nil {
+		t.Fatalf("failed to load server certificate: %v", err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {}}`))
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{serverCert}}
+	srv.StartTLS()
+	defer srv.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	writeTestFile(t, caFile, ca.certPEM, time.Now())
+
+	for serverName, wantErr := range map[string]bool{"": false, "vault.example.com": false, "other.example.com": true} {
+		config := api.DefaultConfig()
+		config.Address = srv.URL
+		config.MaxRetries = 0
+		if _, err := configureTLS(config, caFile, "", "", serverName); err != nil {
+			t.Fatalf("configureTLS returned an error: %v", err)
+		}
+
+		apiClient, err := api.NewClient(config)
+		if err != nil {
+			t.Fatalf("failed to create vault client: %v", err)
+		}
+
+		if _, err := apiClient.Logical().Read("kv/app"); (err != nil) != wantErr {
+			t.Errorf("server name %q: error = %v, wantErr %t", serverName, err, wantErr)
+		}
+	}
+}
+
+// TestTLSFilesClientCertificateReload verifies that the client certificate is
+// reloaded when the files change.
+func TestTLSFilesClientCertificateReload(t *testing.T) {
+	ca := newTestCertificate(t, "vault-ca", nil, &x509.Certificate{})
+	first := newTestCertificate(t, "first", ca, &x509.Certificate{})
+	second := newTestCertificate(t, "second", ca, &x509.Certificate{})
+
+	dir := t.TempDir()
+	files := &tlsFiles{clientCertFile: filepath.Join(dir, "tls.crt"), clientKeyFile: filepath.Join(dir, "tls.key")}
+
+	mtime := time.Now().Add(-time.Minute)
+	writeTestFile(t, files.clientCertFile, first.certPEM, mtime)
+	writeTestFile(t, files.clientKeyFile, first.keyPEM, mtime)
+
+	cert, err := files.getClientCertificate(nil)
+	if err != nil {
+		t.Fatalf("getClientCertificate returned an error: %v", err)
+	}
+	if cert.Leaf.Subject.CommonName != "first" {
+		t.Errorf("common name = %q, want first", cert.Leaf.Subject.CommonName)
+	}
+	if files.changed() {
+		t.Error("expected the files to be unchanged")
+	}
+
+	mtime = time.Now()
+	writeTestFile(t, files.clientCertFile, second.certPEM, mtime)
+	writeTestFile(t, files.clientKeyFile, second.keyPEM, mtime)
+	if !files.changed() {
+		t.Error("expected the files to be changed")
+	}
+
+	cert, err = files.getClientCertificate(nil)
+	if err != nil {
+		t.Fatalf("getClientCertificate returned an error: %v", err)
+	}
+	if cert.Leaf.Subject.CommonName != "second" {
+		t.Errorf("common name = %q, want second", cert.Leaf.Subject.CommonName)
+	}
+}
+
+// TestTLSFilesClientCertificateHalfRotated verifies that a partially written key
+// pair is not reported as changed again, until the remaining file was written,
+// so that the token renewal doesn't request new tokens in a tight loop.
+func TestTLSFilesClientCertificateHalfRotated(t *testing.T) {
+	ca := newTestCertificate(t, "vault-ca", nil, &x509.Certificate{})
+	first := newTestCertificate(t, "first", ca, &x509.Certificate{})
+	second := newTestCertificate(t, "second", ca, &x509.Certificate{})
+
+	dir := t.TempDir()
+	files := &tlsFiles{clientCertFile: filepath.Join(dir, "tls.crt"), clientKeyFile: filepath.Join(dir, "tls.key")}
+
+	mtime := time.Now().Add(-time.Minute)
+	writeTestFile(t, files.clientCertFile, first.certPEM, mtime)
+	writeTestFile(t, files.clientKeyFile, first.keyPEM, mtime)
+	if _, err := files.getClientCertificate(nil); err != nil {
+		t.Fatalf("getClientCertificate returned an error: %v", err)
+	}
+
+	writeTestFile(t, files.clientCertFile, second.certPEM, time.Now().Add(-30*time.Second))
+	if !files.changed() {
+		t.Error("expected the files to be changed")
+	}
+
+	cert, err := files.getClientCertificate(nil)
+	if err != nil {
+		t.Fatalf("getClientCertificate returned an error: %v", err)
+	}
+	if cert.Leaf.Subject.CommonName != "first" {
+		t.Errorf("common name = %q, want first", cert.Leaf.Subject.CommonName)
+	}
+	if files.changed() {
+		t.Error("expected the half rotated files not to be reported as changed again")
+	}
+
+	writeTestFile(t, files.clientKeyFile, second.keyPEM, time.Now())
+	if !files.changed() {
+		t.Error("expected the files to be changed")
+	}
+
+	cert, err = files.getClientCertificate(nil)
+	if err != nil {
+		t.Fatalf("getClientCertificate returned an error: %v", err)
+	}
+	if cert.Leaf.Subject.CommonName != "second" {
+		t.Errorf("common name = %q, want second", cert.Leaf.Subject.CommonName)
+	}
+}
@@ -0,0 +1,27 @@
+package vault
+
+import (
+	"context"
+
+	"github.com/hashicorp/vault/api"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates the spans for the requests against the secrets engines and
+// the lease and revocation requests. The spans are children of the span in the
+// context passed to the request, so that they are part of the trace of the
+// reconciliation. It uses the global tracer provider, which is a no-op provider
+// until tracing is configured.
+var tracer = otel.Tracer("github.com/ricoberger/vault-secrets-operator/internal/vault")
+
+// setRequestID adds the ID of the request, which returned the given secret, as
+// attribute to the span of the given context, so that the request can be found
+// in the audit log of Vault.
+func setRequestID(ctx context.Context, secret *api.Secret) {
+	if secret == nil || secret.RequestID == "" {
+		return
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("vault.request_id", secret.RequestID))
+}
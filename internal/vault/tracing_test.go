@@ -0,0 +1,126 @@
+package vault
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ricoberger/vault-secrets-operator/internal/tracing/tracingtest"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// TestRequestSpans verifies that a span with the path, the mount and the
+// request ID is recorded for every secret which is read from Vault and that the
+// requests against the other secrets engines are recorded as child spans.
+func TestRequestSpans(t *testing.T) {
+	exporter := tracingtest.NewExporter(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv/data/app" {
+			http.Error(w, `{"errors": ["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"request_id": "2a3b8a1f-6c2d-4f5e-9a7b-1c2d3e4f5a6b", "data": {"data": {"username": "admin"}}}`))
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv.URL)
+	client.kvMounts = newKVMountCache(time.Minute, map[string]int{"kv/": 2})
+
+	if _, err := client.GetSecret(context.Background(), "kv/app", nil, 0, false, ""); err != nil {
+		t.Fatalf("GetSecret returned an error: %v", err)
+	}
+	if _, err := client.GetSecret(context.Background(), "kv/other", nil, 0, false, ""); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("spans = %d, want 2", len(spans))
+	}
+
+	attributes := make(map[attribute.Key]string)
+	for _, kv := range spans[0].Attributes {
+		attributes[kv.Key] = kv.Value.Emit()
+	}
+	if spans[0].Name != "GetSecret" || attributes["vault.path"] != "kv/app" || attributes["vault.mount"] != "kv/" || attributes["vault.request_id"] != "2a3b8a1f-6c2d-4f5e-9a7b-1c2d3e4f5a6b" {
+		t.Errorf("span = %s with attributes %v", spans[0].Name, attributes)
+	}
+	if spans[1].Status.Code != codes.Error {
+		t.Errorf("status = %s, want Error for the failed request", spans[1].Status.Code)
+	}
+
+	// The requests against the other secrets engines and the lease and
+	// revocation requests are also recorded as spans with the request ID. The
+	// exporter is reused, because the global tracer provider can only be set
+	// once for the tracer of the package.
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/transit/decrypt/my-key":
+			_, _ = w.Write([]byte(`{"request_id": "request", "data": {"batch_results": [{"plaintext": "c2VjcmV0"}]}}`))
+		case "/v1/sys/leases/revoke":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			_, _ = w.Write([]byte(`{"request_id": "request", "lease_id": "lease", "lease_duration": 3600, "data": {"username": "admin", "access_key": "key", "secret_key": "secret", "signed_key": "ssh-ed25519-cert-v01@openssh.com AAAA"}}`))
+		}
+	}))
+	defer srv.Close()
+	client = newTestClient(t, srv.URL)
+
+	for name, request := range map[string]func(ctx context.Context) error{
+		"GetDatabaseCredentials": func(ctx context.Context) error {
+			_, _, err := client.GetDatabaseCredentials(ctx, "database", "app", "")
+			return err
+		},
+		"GetAWSCredentials": func(ctx context.Context) error {
+			_, _, err := client.GetAWSCredentials(ctx, "aws", "app", nil, "")
+			return err
+		},
+		"SignSSHKey": func(ctx context.Context) error {
+			_, err := client.SignSSHKey(ctx, "ssh", "app", "ssh-ed25519 AAAA", nil, "")
+			return err
+		},
+		"DecryptCiphertexts": func(ctx context.Context) error {
+			_, err := client.DecryptCiphertexts(ctx, "transit", "my-key", map[string]string{"password": "vault:v1:abc"}, nil, false, nil, "")
+			return err
+		},
+		"RenewLease": func(ctx context.Context) error {
+			_, err := client.RenewLease(ctx, "lease", time.Hour, "")
+			return err
+		},
+		"RevokeLease": func(ctx context.Context) error {
+			return client.RevokeLease(ctx, "lease", "")
+		},
+		"RevokeCertificate": func(ctx context.Context) error {
+			return client.RevokeCertificate(ctx, "pki", "01:02", "")
+		},
+	} {
+		exporter.Reset()
+
+		ctx, parent := tracer.Start(context.Background(), "parent")
+		if err := request(ctx); err != nil {
+			t.Fatalf("%s returned an error: %v", name, err)
+		}
+		parent.End()
+
+		spans := exporter.GetSpans()
+		if len(spans) != 2 || spans[0].Name != name || spans[0].Parent.SpanID() != spans[1].SpanContext.SpanID() {
+			t.Errorf("%s: expected a child span of the parent span, got %v", name, spans)
+			continue
+		}
+
+		attributes := make(map[attribute.Key]string)
+		for _, kv := range spans[0].Attributes {
+			attributes[kv.Key] = kv.Value.Emit()
+		}
+		if name != "RevokeLease" && attributes["vault.request_id"] != "request" {
+			t.Errorf("%s: request ID = %q, want request", name, attributes["vault.request_id"])
+		}
+	}
+}
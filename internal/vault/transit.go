@@ -0,0 +1,96 @@
+package vault
+
+import (
+	"context"
+	b64 "encoding/base64"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ricoberger/vault-secrets-operator/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DecryptCiphertexts decrypts the given ciphertexts with the named encryption
+// key of the transit secrets engine mounted under the given path. All
+// ciphertexts are decrypted with a single batch request. Vault returns the
+// plaintexts base64 encoded, so that they are decoded before they are converted
+// via convertData. This means that the keys and isBinary parameters are
+// handled in the same way as for secrets from the KV secrets engine. The
+// options (e.g. "context") are passed to Vault.
+func (c *Client) DecryptCiphertexts(ctx context.Context, path string, key string, ciphertexts map[string]string, keys []string, isBinary bool, options map[string]string, vaultNamespace string) (_ map[string][]byte, err error) {
+	log.Info(fmt.Sprintf("Decrypt ciphertexts with %s/decrypt/%s", path, key))
+
+	ctx, span := tracer.Start(ctx, "DecryptCiphertexts", trace.WithAttributes(attribute.String("vault.path", path), attribute.String("vault.key", key)))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	client, err := c.namespacedClient(vaultNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sort the names of the ciphertexts, so that we can map the results of the
+	// batch request, which are returned in the same order as the batch input,
+	// to the names.
+	names := make([]string, 0, len(ciphertexts))
+	for name := range ciphertexts {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	batchInput := make([]map[string]any, 0, len(names))
+	for _, name := range names {
+		batchInput = append(batchInput, map[string]any{"ciphertext": ciphertexts[name]})
+	}
+
+	reqData := make(map[string]any, len(options)+1)
+	for k, v := range options {
+		reqData[k] = v
+	}
+	reqData["batch_input"] = batchInput
+
+	start := time.Now()
+	r, err := client.Logical().WriteWithContext(ctx, path+"/decrypt/"+key, reqData)
+	observeRequest(requestOperationTransitDecrypt, path, start, err)
+	setRequestID(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	if r == nil {
+		return nil, fmt.Errorf("plaintexts are nil")
+	}
+
+	batchResults, ok := r.Data["batch_results"].([]any)
+	if !ok || len(batchResults) != len(names) {
+		return nil, fmt.Errorf("could not parse batch results")
+	}
+
+	secretData := make(map[string]any, len(names))
+	for i, result := range batchResults {
+		result, ok := result.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("could not parse batch result")
+		}
+
+		if errMsg, ok := result["error"].(string); ok && errMsg != "" {
+			return nil, fmt.Errorf("could not decrypt ciphertext %s: %s", names[i], errMsg)
+		}
+
+		plaintext, ok := result["plaintext"].(string)
+		if !ok {
+			return nil, fmt.Errorf("could not parse plaintext for ciphertext %s", names[i])
+		}
+
+		decoded, err := b64.StdEncoding.DecodeString(plaintext)
+		if err != nil {
+			return nil, err
+		}
+
+		secretData[names[i]] = string(decoded)
+	}
+
+	return convertData(secretData, keys, isBinary)
+}
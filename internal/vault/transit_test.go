@@ -0,0 +1,133 @@
+package vault
+
+import (
+	"context"
+	b64 "encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTransitTestServer returns a test server, which emulates the decrypt
+// endpoint of the transit secrets engine. The plaintexts are returned in the
+// order of the batch input.
+func newTransitTestServer(t *testing.T, plaintexts map[string]string, gotPath *string, gotBody *map[string]any) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*gotPath = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(gotBody)
+
+		var results []map[string]string
+		for _, input := range (*gotBody)["batch_input"].([]any) {
+			ciphertext := input.(map[string]any)["ciphertext"].(string)
+			results = append(results, map[string]string{
+				"plaintext": b64.StdEncoding.EncodeToString([]byte(plaintexts[ciphertext])),
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"data": map[string]any{"batch_results": results},
+		})
+	}))
+}
+
+// TestDecryptCiphertexts verifies that all ciphertexts are decrypted with a
+// single batch request and that the plaintexts are mapped to their keys.
+func TestDecryptCiphertexts(t *testing.T) {
+	var (
+		gotPath string
+		gotBody map[string]any
+	)
+
+	srv := newTransitTestServer(t, map[string]string{
+		"vault:v1:username": "admin",
+		"vault:v1:password": "s3cr3t",
+	}, &gotPath, &gotBody)
+	defer srv.Close()
+
+	client := newTestClient(t, srv.URL)
+
+	data, err := client.DecryptCiphertexts(context.Background(), "transit", "my-key", map[string]string{
+		"username": "vault:v1:username",
+		"password": "vault:v1:password",
+	}, nil, false, map[string]string{"context": "Y29udGV4dA=="}, "")
+	if err != nil {
+		t.Fatalf("DecryptCiphertexts returned an error: %v", err)
+	}
+
+	if gotPath != "/v1/transit/decrypt/my-key" {
+		t.Errorf("path = %q, want /v1/transit/decrypt/my-key", gotPath)
+	}
+	if got := gotBody["context"]; got != "Y29udGV4dA==" {
+		t.Errorf("context = %v, want Y29udGV4dA==", got)
+	}
+	if got := string(data["username"]); got != "admin" {
+		t.Errorf("username = %q, want admin", got)
+	}
+	if got := string(data["password"]); got != "s3cr3t" {
+		t.Errorf("password = %q, want s3cr3t", got)
+	}
+}
+
+// TestDecryptCiphertextsBinary verifies that base64 encoded plaintexts are
+// decoded when isBinary is set and that the keys parameter is respected.
+func TestDecryptCiphertextsBinary(t *testing.T) {
+	var (
+		gotPath string
+		gotBody map[string]any
+	)
+
+	srv := newTransitTestServer(t, map[string]string{
+		"vault:v1:keystore": "YmFyCg==",
+		"vault:v1:other":    "b3RoZXI=",
+	}, &gotPath, &gotBody)
+	defer srv.Close()
+
+	client := newTestClient(t, srv.URL)
+
+	data, err := client.DecryptCiphertexts(context.Background(), "transit", "my-key", map[string]string{
+		"keystore": "vault:v1:keystore",
+		"other":    "vault:v1:other",
+	}, []string{"keystore"}, true, nil, "")
+	if err != nil {
+		t.Fatalf("DecryptCiphertexts returned an error: %v", err)
+	}
+
+	if len(data) != 1 {
+		t.Fatalf("got %d keys, want 1: %v", len(data), data)
+	}
+	if got := string(data["keystore"]); got != "bar\n" {
+		t.Errorf("keystore = %q, want %q", got, "bar\n")
+	}
+}
+
+// TestDecryptCiphertextsError verifies that an error for a single ciphertext
+// fails the whole request.
+func TestDecryptCiphertextsError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"batch_results": [{"error": "invalid ciphertext: no prefix"}]}}`))
+	}))
+	defer srv.Close()
+
+	client := newTestClient(t, srv.URL)
+
+	if _, err := client.DecryptCiphertexts(context.Background(), "transit", "my-key", map[string]string{"password": "invalid"}, nil, false, nil, ""); err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+}
+
+// TestDecryptCiphertextsNamespace verifies that the namespace of a secret is
+// used to decrypt the ciphertexts.
+func TestDecryptCiphertextsNamespace(t *testing.T) {
+	client, namespaces := newTestNamespaceClient(t, `{"data": {"batch_results": [{"plaintext": "c2VjcmV0"}]}}`)
+
+	if _, err := client.DecryptCiphertexts(context.Background(), "transit", "my-key", map[string]string{"password": "vault:v1:abc"}, nil, false, nil, "team-a"); err != nil {
+		t.Fatalf("DecryptCiphertexts returned an error: %v", err)
+	}
+
+	assertNamespaces(t, namespaces())
+}
@@ -36,8 +36,25 @@ var (
 	// ReconciliationTime specify the time in seconds after a vault secret is
 	// reconciled.
 	ReconciliationTime int
+
+	// ServiceAccountTokenRequest specifies if the operator should request a
+	// token for a service account in the namespace of a secret via the
+	// TokenRequest API, to authenticate against Vault with the vaultRole of the
+	// secret. It can be enabled via the VAULT_KUBERNETES_TOKEN_REQUEST
+	// environment variable.
+	ServiceAccountTokenRequest bool
+
+	// ServiceAccountTokenAudiences are the audiences of the requested service
+	// account tokens. If no audiences are specified via the
+	// VAULT_KUBERNETES_TOKEN_AUDIENCES environment variable, the default
+	// audience of the Kubernetes API server is used.
+	ServiceAccountTokenAudiences []string
 )
 
+// ServiceAccountToken returns the token of a Kubernetes service account, which
+// is used to authenticate against Vault with the Kubernetes or JWT auth method.
+type ServiceAccountToken func() (string, error)
+
 // InitSharedClient is used to initialize the shared client, when the
 // VAULT_KUBERNETES_ROLE is specified.
 func InitSharedClient() error {
@@ -54,6 +71,18 @@ func InitSharedClient() error {
 		log.WithValues("ReconciliationTime", ReconciliationTime).Info("Reconciliation is enabled.")
 	}
 
+	// Parse the environment variables for the service account tokens, which
+	// are requested for secrets with the vaultRole property.
+	if ServiceAccountTokenRequest, err = strconv.ParseBool(os.Getenv("VAULT_KUBERNETES_TOKEN_REQUEST")); err != nil {
+		ServiceAccountTokenRequest = false
+	}
+	ServiceAccountTokenAudiences = nil
+	for audience := range strings.SplitSeq(os.Getenv("VAULT_KUBERNETES_TOKEN_AUDIENCES"), ",") {
+		if audience = strings.TrimSpace(audience); audience != "" {
+			ServiceAccountTokenAudiences = append(ServiceAccountTokenAudiences, audience)
+		}
+	}
+
 	vaultKubernetesRole := os.Getenv("VAULT_KUBERNETES_ROLE")
 	SharedClient, err = CreateClient(vaultKubernetesRole)
 	if err != nil {
@@ -65,8 +94,17 @@ func InitSharedClient() error {
 
 // CreateClient is used by the InitSharedClient and directly for a
 // reconciliation loop to create a new Vault client.
-// nolint:gocyclo
 func CreateClient(vaultKubernetesRole string) (*Client, error) {
+	return CreateClientWithServiceAccountToken(vaultKubernetesRole, nil)
+}
+
+// CreateClientWithServiceAccountToken creates a new Vault client like
+// CreateClient, but uses the given function to get the service account token
+// for the Kubernetes and JWT auth methods, instead of reading the token of the
+// operator from a file. If the function is nil the token of the operator is
+// used.
+// nolint:gocyclo
+func CreateClientWithServiceAccountToken(vaultKubernetesRole string, serviceAccountToken ServiceAccountToken) (*Client, error) {
 	vaultAddress := os.Getenv("VAULT_ADDRESS")
 	vaultHeader := os.Getenv("VAULT_HEADER")
 	vaultAuthMethod := os.Getenv("VAULT_AUTH_METHOD")
@@ -79,7 +117,10 @@ func CreateClient(vaultKubernetesRole string) (*Client, error) {
 	vaultTokenRenewalInterval := os.Getenv("VAULT_TOKEN_RENEWAL_INTERVAL")
 	vaultTokenRenewalRetryInterval := os.Getenv("VAULT_TOKEN_RENEWAL_RETRY_INTERVAL")
 	vaultKubernetesPath := os.Getenv("VAULT_KUBERNETES_PATH")
+	vaultJWTPath := os.Getenv("VAULT_JWT_PATH")
 	vaultAppRolePath := os.Getenv("VAULT_APP_ROLE_PATH")
+	vaultCertPath := os.Getenv("VAULT_CERT_PATH")
+	vaultCertRole := os.Getenv("VAULT_CERT_ROLE")
 	vaultAzurePath := os.Getenv("VAULT_AZURE_PATH")
 	vaultAzureRole := os.Getenv("VAULT_AZURE_ROLE")
 	vaultAzureIsScaleset := os.Getenv("VAULT_AZURE_ISSCALESET")
@@ -94,6 +135,13 @@ func CreateClient(vaultKubernetesRole string) (*Client, error) {
 	vaultTokenMaxTTL := os.Getenv("VAULT_TOKEN_MAX_TTL")
 	vaultNamespace := os.Getenv("VAULT_NAMESPACE")
 	vaultPKIRenew := os.Getenv("VAULT_PKI_RENEW")
+	vaultLeaseRenew := os.Getenv("VAULT_LEASE_RENEW")
+	vaultKVMountCacheTTL := os.Getenv("VAULT_KV_MOUNT_CACHE_TTL")
+	vaultKVVersions := os.Getenv("VAULT_KV_VERSIONS")
+	vaultTLSCACert := os.Getenv("VAULT_TLS_CA_CERT")
+	vaultTLSClientCert := os.Getenv("VAULT_TLS_CLIENT_CERT")
+	vaultTLSClientKey := os.Getenv("VAULT_TLS_CLIENT_KEY")
+	vaultTLSServerName := os.Getenv("VAULT_TLS_SERVER_NAME")
 
 	// Create new Vault configuration. This configuration is used to create the
 	// API client. We set the timeout of the HTTP client to 10 seconds.
@@ -101,6 +149,13 @@ func CreateClient(vaultKubernetesRole string) (*Client, error) {
 	config := api.DefaultConfig()
 	config.Address = vaultAddress
 
+	// Configure the CA bundle, the client certificate and the server name for
+	// the TLS connection to Vault. The files are reloaded when they change.
+	certFiles, err := configureTLS(config, vaultTLSCACert, vaultTLSClientCert, vaultTLSClientKey, vaultTLSServerName)
+	if err != nil {
+		return nil, err
+	}
+
 	apiClient, err := api.NewClient(config)
 	if err != nil {
 		return nil, err
@@ -120,6 +175,36 @@ func CreateClient(vaultKubernetesRole string) (*Client, error) {
 		return nil, err
 	}
 
+	if len(vaultLeaseRenew) == 0 {
+		vaultLeaseRenew = "5m"
+	}
+
+	leaseRenew, err := time.ParseDuration(vaultLeaseRenew)
+	if err != nil {
+		return nil, err
+	}
+
+	// The mount information of the KV secrets engines is cached for 5 minutes
+	// by default, so that we do not have to run a preflight request before
+	// every read of a secret. The VAULT_KV_VERSIONS environment variable can
+	// be used to set the version of a KV secrets engine statically, when the
+	// operator is not allowed to access the "sys/internal/ui/mounts" endpoint.
+	if len(vaultKVMountCacheTTL) == 0 {
+		vaultKVMountCacheTTL = "5m"
+	}
+
+	kvMountCacheTTL, err := time.ParseDuration(vaultKVMountCacheTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	kvVersions, err := parseKVVersions(vaultKVVersions)
+	if err != nil {
+		return nil, err
+	}
+
+	kvMounts := newKVMountCache(kvMountCacheTTL, kvVersions)
+
 	vaultRestrictNamespace, err := strconv.ParseBool(os.Getenv("VAULT_RESTRICT_NAMESPACE"))
 	if err != nil {
 		vaultRestrictNamespace = false
@@ -179,6 +264,9 @@ func CreateClient(vaultKubernetesRole string) (*Client, error) {
 			rootVaultNamespace:        vaultNamespace,
 			restrictNamespace:         vaultRestrictNamespace,
 			pkiRenew:                  pkiRenew,
+			leaseRenew:                leaseRenew,
+			kvMounts:                  kvMounts,
+			authMethod:                vaultAuthMethod,
 		}, nil
 	}
 
@@ -205,23 +293,37 @@ func CreateClient(vaultKubernetesRole string) (*Client, error) {
 			serviceAccountTokenPath = vaultTokenPath
 		}
 
+		// readServiceAccountToken returns the token of the service account,
+		// which was requested for the secret or the token of the operator.
+		readServiceAccountToken := func() (string, error) {
+			if serviceAccountToken != nil {
+				return serviceAccountToken()
+			}
+
+			//nolint:gosec
+			kubeToken, err := os.ReadFile(serviceAccountTokenPath)
+			if err != nil {
+				return "", err
+			}
+			return string(kubeToken), nil
+		}
+
 		// Read the service account token value and create a map for the
 		// authentication against Vault.
-		//nolint:gosec
-		kubeToken, err := os.ReadFile(serviceAccountTokenPath)
+		kubeToken, err := readServiceAccountToken()
 		if err != nil {
 			return nil, err
 		}
 
 		data := make(map[string]any)
-		data["jwt"] = string(kubeToken)
+		data["jwt"] = kubeToken
 		data["role"] = vaultKubernetesRole
 
 		// Authenticate against vault using the Kubernetes Auth Method and set
 		// the token which the client should use for further interactions with
 		// Vault. We also set the lease duration of the token for the renew
 		// function.
-		secret, err := apiClient.Logical().Write(vaultKubernetesPath+"/login", data)
+		secret, err := login(apiClient, vaultKubernetesPath, data)
 		if err != nil {
 			return nil, err
 		} else if secret.Auth == nil {
@@ -262,37 +364,147 @@ func CreateClient(vaultKubernetesRole string) (*Client, error) {
 			requestToken: func(c *Client) error {
 				// Read the service account token value and create a map for the
 				// authentication against Vault again as the token might have changed.
-				//nolint:gosec
-				kubeToken, err := os.ReadFile(serviceAccountTokenPath)
+				kubeToken, err := readServiceAccountToken()
 				if err != nil {
 					return err
 				}
 
 				data := make(map[string]any)
-				data["jwt"] = string(kubeToken)
+				data["jwt"] = kubeToken
 				data["role"] = vaultKubernetesRole
 
 				// Reauthenticate with Vault and update the token for further
 				// interactions with Vault.
-				secret, err := apiClient.Logical().Write(vaultKubernetesPath+"/login", data)
+				secret, err := login(apiClient, vaultKubernetesPath, data)
 				if err != nil {
 					return err
 				} else if secret.Auth == nil {
 					return fmt.Errorf("missing authentication information")
 				}
 
-				c.client.SetToken(secret.Auth.ClientToken)
+				// Update the token, the token lease duration and the renewal
+				// interval.
+				return c.setToken(secret.Auth, vaultTokenRenewalInterval)
+			},
+			pkiRenew:   pkiRenew,
+			leaseRenew: leaseRenew,
+			kvMounts:   kvMounts,
+			authMethod: vaultAuthMethod,
+		}, nil
+	}
+
+	if vaultAuthMethod == "jwt" {
+		// The JWT Auth Method uses the same role as the Kubernetes Auth
+		// Method, so that the vaultRole property of a secret can be used with
+		// both auth methods. If the role is missing we return nil for the
+		// shared client, like for the Kubernetes Auth Method.
+		if vaultKubernetesRole == "" {
+			return nil, nil
+		}
+
+		jwtPath := "auth/jwt"
+		if vaultJWTPath != "" {
+			jwtPath = vaultJWTPath
+		}
 
-				// Update token lease duration and renewal interval
-				c.tokenLeaseDuration = secret.Auth.LeaseDuration
-				c.tokenRenewalInterval, err = strconv.ParseFloat(vaultTokenRenewalInterval, 64)
+		// The projected service account token is mounted by the Helm chart
+		// with the audience, which is expected by Vault. Since Vault verifies
+		// the token with the public keys of the cluster, it does not need
+		// access to the TokenReview API.
+		// #nosec G101
+		jwtTokenPath := "/var/run/secrets/vault/token"
+		if vaultTokenPath != "" {
+			jwtTokenPath = vaultTokenPath
+		}
+
+		// jwtLogin reads the projected service account token and authenticates
+		// against Vault. The token is read on every login, because it is
+		// rotated by the kubelet before it expires. If a function to get the
+		// service account token of a secret is provided, it is used instead.
+		jwtLogin := func() (*api.Secret, error) {
+			var jwt []byte
+			if serviceAccountToken != nil {
+				token, err := serviceAccountToken()
 				if err != nil {
-					c.tokenRenewalInterval = float64(c.tokenLeaseDuration) * 0.5
+					return nil, err
 				}
+				jwt = []byte(token)
+			} else {
+				//nolint:gosec
+				token, err := os.ReadFile(jwtTokenPath)
+				if err != nil {
+					return nil, err
+				}
+				jwt = token
+			}
+
+			data := make(map[string]any)
+			data["jwt"] = strings.TrimSpace(string(jwt))
+			data["role"] = vaultKubernetesRole
+
+			secret, err := login(apiClient, jwtPath, data)
+			if err != nil {
+				return nil, err
+			} else if secret == nil || secret.Auth == nil {
+				return nil, fmt.Errorf("missing authentication information")
+			}
+
+			return secret, nil
+		}
+
+		// Authenticate against vault using the JWT Auth Method and set the
+		// token which the client should use for further interactions with
+		// Vault. We also set the lease duration of the token for the renew
+		// function.
+		secret, err := jwtLogin()
+		if err != nil {
+			return nil, err
+		}
+
+		tokenLeaseDuration := secret.Auth.LeaseDuration
+
+		tokenRenewalInterval, err := strconv.ParseFloat(vaultTokenRenewalInterval, 64)
+		if err != nil {
+			tokenRenewalInterval = float64(tokenLeaseDuration) * 0.5
+		}
+
+		tokenRenewalRetryInterval, err := strconv.ParseFloat(vaultTokenRenewalRetryInterval, 64)
+		if err != nil {
+			tokenRenewalRetryInterval = 30.0
+		}
+
+		tokenMaxTTL, err := strconv.Atoi(vaultTokenMaxTTL)
+		if err != nil {
+			// Vault default max TTL is 32 days, use 16 days as the reasonable
+			// default if VAULT_TOKEN_MAX_TTL not set.
+			// https://learn.hashicorp.com/tutorials/vault/tokens
+			tokenMaxTTL = 16 * 24 * 60 * 60
+		}
+
+		apiClient.SetToken(secret.Auth.ClientToken)
 
-				return nil
+		return &Client{
+			client:                    apiClient,
+			renewToken:                renewToken,
+			tokenLeaseDuration:        tokenLeaseDuration,
+			tokenRenewalInterval:      tokenRenewalInterval,
+			tokenRenewalRetryInterval: tokenRenewalRetryInterval,
+			tokenMaxTTL:               tokenMaxTTL,
+			rootVaultNamespace:        vaultNamespace,
+			restrictNamespace:         vaultRestrictNamespace,
+			requestToken: func(c *Client) error {
+				secret, err := jwtLogin()
+				if err != nil {
+					return err
+				}
+				// Update the token, the token lease duration and the renewal
+				// interval.
+				return c.setToken(secret.Auth, vaultTokenRenewalInterval)
 			},
-			pkiRenew: pkiRenew,
+			pkiRenew:   pkiRenew,
+			leaseRenew: leaseRenew,
+			kvMounts:   kvMounts,
+			authMethod: vaultAuthMethod,
 		}, nil
 	}
 
@@ -320,7 +532,7 @@ func CreateClient(vaultKubernetesRole string) (*Client, error) {
 		// the token which the client should use for further interactions with
 		// Vault. We also set the lease duration of the token for the renew
 		// function.
-		secret, err := apiClient.Logical().Write(appRolePath+"/login", data)
+		secret, err := login(apiClient, appRolePath, data)
 		if err != nil {
 			return nil, err
 		} else if secret.Auth == nil {
@@ -359,20 +571,100 @@ func CreateClient(vaultKubernetesRole string) (*Client, error) {
 			rootVaultNamespace:        vaultNamespace,
 			restrictNamespace:         vaultRestrictNamespace,
 			requestToken: func(c *Client) error {
-				secret, err := apiClient.Logical().Write(appRolePath+"/login", data)
+				secret, err := login(apiClient, appRolePath, data)
 				if err != nil {
 					return err
 				}
-				c.client.SetToken(secret.Auth.ClientToken)
-				// Update token lease duration and renewal interval
-				c.tokenLeaseDuration = secret.Auth.LeaseDuration
-				c.tokenRenewalInterval, err = strconv.ParseFloat(vaultTokenRenewalInterval, 64)
+				// Update the token, the token lease duration and the renewal
+				// interval.
+				return c.setToken(secret.Auth, vaultTokenRenewalInterval)
+			},
+			pkiRenew:   pkiRenew,
+			leaseRenew: leaseRenew,
+			kvMounts:   kvMounts,
+			authMethod: vaultAuthMethod,
+		}, nil
+	}
+
+	if vaultAuthMethod == "cert" {
+		if vaultTLSClientCert == "" || vaultTLSClientKey == "" {
+			return nil, fmt.Errorf("missing client certificate for cert auth method")
+		}
+
+		certPath := "auth/cert"
+		if vaultCertPath != "" {
+			certPath = vaultCertPath
+		}
+
+		data := make(map[string]any)
+		if vaultCertRole != "" {
+			data["name"] = vaultCertRole
+		}
+
+		// Authenticate against vault using the TLS Certificate Auth Method and
+		// set the token which the client should use for further interactions
+		// with Vault. The client certificate is sent during the TLS handshake,
+		// so that the login request itself does not contain any credentials.
+		secret, err := login(apiClient, certPath, data)
+		if err != nil {
+			return nil, err
+		} else if secret.Auth == nil {
+			return nil, fmt.Errorf("missing authentication information")
+		}
+
+		tokenLeaseDuration := secret.Auth.LeaseDuration
+
+		tokenRenewalInterval, err := strconv.ParseFloat(vaultTokenRenewalInterval, 64)
+		if err != nil {
+			tokenRenewalInterval = float64(tokenLeaseDuration) * 0.5
+		}
+
+		tokenRenewalRetryInterval, err := strconv.ParseFloat(vaultTokenRenewalRetryInterval, 64)
+		if err != nil {
+			tokenRenewalRetryInterval = 30.0
+		}
+
+		tokenMaxTTL, err := strconv.Atoi(vaultTokenMaxTTL)
+		if err != nil {
+			// Vault default max TTL is 32 days, use 16 days as the reasonable
+			// default if VAULT_TOKEN_MAX_TTL not set.
+			// https://learn.hashicorp.com/tutorials/vault/tokens
+			tokenMaxTTL = 16 * 24 * 60 * 60
+		}
+
+		apiClient.SetToken(secret.Auth.ClientToken)
+
+		return &Client{
+			client:                    apiClient,
+			renewToken:                renewToken,
+			tokenLeaseDuration:        tokenLeaseDuration,
+			tokenRenewalInterval:      tokenRenewalInterval,
+			tokenRenewalRetryInterval: tokenRenewalRetryInterval,
+			tokenMaxTTL:               tokenMaxTTL,
+			rootVaultNamespace:        vaultNamespace,
+			restrictNamespace:         vaultRestrictNamespace,
+			requestToken: func(c *Client) error {
+				// Reload the client certificate and close the idle connections,
+				// so that the login request uses a new TLS connection with the
+				// rotated certificate.
+				if _, err := certFiles.getClientCertificate(nil); err != nil {
+					return err
+				}
+				config.HttpClient.CloseIdleConnections()
+
+				secret, err := login(apiClient, certPath, data)
 				if err != nil {
-					c.tokenRenewalInterval = float64(c.tokenLeaseDuration) * 0.5
+					return err
 				}
-				return nil
+				// Update the token, the token lease duration and the renewal
+				// interval.
+				return c.setToken(secret.Auth, vaultTokenRenewalInterval)
 			},
-			pkiRenew: pkiRenew,
+			certFiles:  certFiles,
+			pkiRenew:   pkiRenew,
+			leaseRenew: leaseRenew,
+			kvMounts:   kvMounts,
+			authMethod: vaultAuthMethod,
 		}, nil
 	}
 
@@ -388,7 +680,9 @@ func CreateClient(vaultKubernetesRole string) (*Client, error) {
 		data["password"] = vaultPassword
 
 		userPassLoginPath := "auth/userpass/login/" + vaultUser
+		start := time.Now()
 		secret, err := apiClient.Logical().Write(userPassLoginPath, data)
+		observeRequest(requestOperationLogin, "auth/userpass", start, err)
 		if err != nil {
 			return nil, err
 		}
@@ -428,7 +722,9 @@ func CreateClient(vaultKubernetesRole string) (*Client, error) {
 			rootVaultNamespace:        vaultNamespace,
 			restrictNamespace:         vaultRestrictNamespace,
 			requestToken: func(c *Client) error {
+				start := time.Now()
 				secret, err := apiClient.Logical().Write(userPassLoginPath, data)
+				observeRequest(requestOperationLogin, "auth/userpass", start, err)
 				if err != nil {
 					return err
 				}
@@ -436,17 +732,14 @@ func CreateClient(vaultKubernetesRole string) (*Client, error) {
 					return fmt.Errorf("missing authentication information")
 				}
 
-				c.client.SetToken(secret.Auth.ClientToken)
-				// Update token lease duration and renewal interval
-				c.tokenLeaseDuration = secret.Auth.LeaseDuration
-				c.tokenRenewalInterval, err = strconv.ParseFloat(vaultTokenRenewalInterval, 64)
-				if err != nil {
-					c.tokenRenewalInterval = float64(c.tokenLeaseDuration) * 0.5
-				}
-
-				return nil
+				// Update the token, the token lease duration and the renewal
+				// interval.
+				return c.setToken(secret.Auth, vaultTokenRenewalInterval)
 			},
-			pkiRenew: pkiRenew,
+			pkiRenew:   pkiRenew,
+			leaseRenew: leaseRenew,
+			kvMounts:   kvMounts,
+			authMethod: vaultAuthMethod,
 		}, nil
 	}
 
@@ -492,7 +785,7 @@ func CreateClient(vaultKubernetesRole string) (*Client, error) {
 		// the token which the client should use for further interactions with
 		// Vault. We also set the lease duration of the token for the renew
 		// function.
-		secret, err := apiClient.Logical().Write(vaultAzurePath+"/login", data)
+		secret, err := login(apiClient, vaultAzurePath, data)
 		if err != nil {
 			return nil, err
 		} else if secret.Auth == nil {
@@ -522,6 +815,9 @@ func CreateClient(vaultKubernetesRole string) (*Client, error) {
 			rootVaultNamespace:        vaultNamespace,
 			restrictNamespace:         vaultRestrictNamespace,
 			pkiRenew:                  pkiRenew,
+			leaseRenew:                leaseRenew,
+			kvMounts:                  kvMounts,
+			authMethod:                vaultAuthMethod,
 		}, nil
 	}
 
@@ -590,16 +886,14 @@ func CreateClient(vaultKubernetesRole string) (*Client, error) {
 				if err != nil {
 					return err
 				}
-				c.client.SetToken(secret.Auth.ClientToken)
-				// Update token lease duration and renewal interval
-				c.tokenLeaseDuration = secret.Auth.LeaseDuration
-				c.tokenRenewalInterval, err = strconv.ParseFloat(vaultTokenRenewalInterval, 64)
-				if err != nil {
-					c.tokenRenewalInterval = float64(c.tokenLeaseDuration) * 0.5
-				}
-				return nil
+				// Update the token, the token lease duration and the renewal
+				// interval.
+				return c.setToken(secret.Auth, vaultTokenRenewalInterval)
 			},
-			pkiRenew: pkiRenew,
+			pkiRenew:   pkiRenew,
+			leaseRenew: leaseRenew,
+			kvMounts:   kvMounts,
+			authMethod: vaultAuthMethod,
 		}, nil
 	}
 
@@ -691,7 +985,7 @@ func CreateClient(vaultKubernetesRole string) (*Client, error) {
 		// the token which the client should use for further interactions with
 		// Vault. We also set the lease duration of the token for the renew
 		// function.
-		secret, err := apiClient.Logical().Write(vaultGcpPath+"/login", data)
+		secret, err := login(apiClient, vaultGcpPath, data)
 		if err != nil {
 			return nil, err
 		} else if secret.Auth == nil {
@@ -725,20 +1019,18 @@ func CreateClient(vaultKubernetesRole string) (*Client, error) {
 				if err != nil {
 					return err
 				}
-				secret, err := apiClient.Logical().Write(vaultGcpPath+"/login", data)
+				secret, err := login(apiClient, vaultGcpPath, data)
 				if err != nil {
 					return err
 				}
-				c.client.SetToken(secret.Auth.ClientToken)
-				// Update token lease duration and renewal interval
-				c.tokenLeaseDuration = secret.Auth.LeaseDuration
-				c.tokenRenewalInterval, err = strconv.ParseFloat(vaultTokenRenewalInterval, 64)
-				if err != nil {
-					c.tokenRenewalInterval = float64(c.tokenLeaseDuration) * 0.5
-				}
-				return nil
+				// Update the token, the token lease duration and the renewal
+				// interval.
+				return c.setToken(secret.Auth, vaultTokenRenewalInterval)
 			},
-			pkiRenew: pkiRenew,
+			pkiRenew:   pkiRenew,
+			leaseRenew: leaseRenew,
+			kvMounts:   kvMounts,
+			authMethod: vaultAuthMethod,
 		}, nil
 	}
 
@@ -773,3 +1065,12 @@ func setVaultIDs(idType string) string {
 
 	return string(id)
 }
+
+// login authenticates against the auth method mounted under the given path by
+// writing the given data to the login endpoint of the auth method.
+func login(client *api.Client, path string, data map[string]any) (*api.Secret, error) {
+	start := time.Now()
+	secret, err := client.Logical().Write(path+"/login", data)
+	observeRequest(requestOperationLogin, path, start, err)
+	return secret, err
+}